@@ -0,0 +1,159 @@
+package sqinn
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// BatchOptions control how ExecBatch handles failing iterations.
+type BatchOptions struct {
+	// ContinueOnError tells ExecBatch to skip a failing iteration and
+	// continue with the next one. The failing iteration is rolled back,
+	// all other iterations are committed.
+	// Default is false (stop at the first failing iteration).
+	ContinueOnError bool
+
+	// ChunkSize is the number of iterations that are executed together,
+	// inside one savepoint. If an iteration of a chunk fails, the chunk
+	// is rolled back and executed again, one iteration at a time, to find
+	// the failing iteration.
+	// Default is 1000.
+	ChunkSize int
+}
+
+// A BatchError describes a failed iteration of ExecBatch.
+type BatchError struct {
+	Iteration int     // The iteration index, starting at 0.
+	Params    []Value // The parameter values of the failed iteration.
+	Err       error   // The error reported by sqinn.
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("iteration %d: %s", e.Iteration, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch is like Exec but reports which iteration failed.
+//
+// Iterations are executed in chunks, each chunk inside a SAVEPOINT.
+// If a chunk fails, it is rolled back and replayed iteration by iteration,
+// each iteration inside its own SAVEPOINT, to find the failing iteration.
+//
+// If opt.ContinueOnError is false, ExecBatch stops at the first failing
+// iteration and returns a *BatchError. All iterations before the failing
+// iteration are committed, just like Exec would do.
+//
+// If opt.ContinueOnError is true, ExecBatch rolls back failing iterations,
+// commits all others, and returns the failed iterations as a list of
+// *BatchError values. The returned error is then only non-nil if the
+// batch itself could not be executed, e.g. because of an I/O error.
+//
+// An error that does not depend on the parameter values, e.g. a syntax
+// error or a missing table, is returned once, as is, and not as a
+// *BatchError for each iteration.
+//
+// ExecBatch holds the sqinn instance for the whole batch, so no other calls
+// are interleaved with its savepoints.
+//
+// Since failing chunks are replayed, ExecBatch holds the parameter values
+// of one chunk in memory. A ProduceFunc must therefore not modify
+// a Blob after it was assigned to a parameter value.
+func (sq *Sqinn) ExecBatch(sql string, niterations, nparams int, produce ProduceFunc, opt BatchOptions) ([]*BatchError, error) {
+	if niterations < 0 {
+		return nil, sq.argError("ExecBatch", "invalid niterations < 0")
+	}
+	if nparams < 0 {
+		return nil, sq.argError("ExecBatch", "invalid nparams < 0")
+	}
+	if nparams > 0 && produce == nil {
+		return nil, sq.argError("ExecBatch", "invalid nparams > 0 && produce == nil")
+	}
+	if n := countParams(sql); niterations > 0 && n != nparams {
+		return nil, &ArgError{"ExecBatch", fmt.Sprintf("nparams is %d but SQL has %d parameters", nparams, n)}
+	}
+	chunkSize := opt.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	var failures []*BatchError
+	params := make([]Value, min(chunkSize, niterations)*nparams)
+	for start := 0; start < niterations; start += chunkSize {
+		n := min(chunkSize, niterations-start)
+		chunk := params[:n*nparams]
+		if nparams > 0 {
+			for i := range n {
+				produce(start+i, chunk[i*nparams:(i+1)*nparams])
+			}
+		}
+		if err := sq.execSql("SAVEPOINT sqinn_batch"); err != nil {
+			return failures, err
+		}
+		if err := sq.execParams("ExecBatch", sql, n, nparams, chunk); err == nil {
+			if err := sq.execSql("RELEASE sqinn_batch"); err != nil {
+				return failures, err
+			}
+			continue // with next chunk
+		}
+		// replay chunk, one iteration at a time
+		if err := sq.execSql("ROLLBACK TO sqinn_batch"); err != nil {
+			return failures, err
+		}
+		// an error that does not depend on the params, like a syntax error,
+		// would fail every iteration, so report it only once
+		if err := sq.exec("ExecBatch", sql, 0, nparams, nil); err != nil {
+			return failures, errors.Join(err, sq.execSql("RELEASE sqinn_batch"))
+		}
+		for i := range n {
+			iterationParams := chunk[i*nparams : (i+1)*nparams]
+			execErr, err := sq.execIteration(sql, nparams, iterationParams)
+			if err != nil {
+				return failures, err
+			}
+			if execErr == nil {
+				continue // with next iteration
+			}
+			failure := &BatchError{start + i, slices.Clone(iterationParams), execErr}
+			if !opt.ContinueOnError {
+				if err := sq.execSql("RELEASE sqinn_batch"); err != nil {
+					return failures, err
+				}
+				return failures, failure
+			}
+			failures = append(failures, failure)
+		}
+		if err := sq.execSql("RELEASE sqinn_batch"); err != nil {
+			return failures, err
+		}
+	}
+	return failures, nil
+}
+
+// MustExecBatch is the same as ExecBatch except it panics on error.
+func (sq *Sqinn) MustExecBatch(sql string, niterations, nparams int, produce ProduceFunc, opt BatchOptions) []*BatchError {
+	return must(sq.ExecBatch(sql, niterations, nparams, produce, opt))
+}
+
+// execIteration executes one iteration inside a savepoint. It returns the
+// iteration's error in execErr, and errors from savepoint handling in err.
+// The caller must hold sq.mu.
+func (sq *Sqinn) execIteration(sql string, nparams int, params []Value) (execErr error, err error) {
+	if err := sq.execSql("SAVEPOINT sqinn_batch_iteration"); err != nil {
+		return nil, err
+	}
+	execErr = sq.execParams("ExecBatch", sql, 1, nparams, params)
+	if execErr != nil {
+		if err := sq.execSql("ROLLBACK TO sqinn_batch_iteration"); err != nil {
+			return execErr, err
+		}
+	}
+	if err := sq.execSql("RELEASE sqinn_batch_iteration"); err != nil {
+		return execErr, err
+	}
+	return execErr, nil
+}
@@ -0,0 +1,132 @@
+package sqinn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecBatch(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL)")
+	countUsers := func() int {
+		rows := sq.MustQueryRows("SELECT COUNT(*) FROM users", nil, []byte{ValInt32})
+		return rows[0][0].Int32
+	}
+	// produce user ids 1,2,3,...,n where the user at failIteration has a NULL name
+	produceUsers := func(failIterations ...int) ProduceFunc {
+		return func(iteration int, params []Value) {
+			params[0] = Int32Value(iteration + 1)
+			params[1] = StringValue("User")
+			for _, failIteration := range failIterations {
+				if iteration == failIteration {
+					params[1] = NullValue()
+				}
+			}
+		}
+	}
+	// no failures
+	failures, err := sq.ExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 10, 2, produceUsers(), BatchOptions{ChunkSize: 3})
+	isNoErr(t, err)
+	isEq(t, 0, len(failures))
+	isEq(t, 10, countUsers())
+	// niterations 0 is a NO-OP
+	failures, err = sq.ExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 0, 2, produceUsers(), BatchOptions{})
+	isNoErr(t, err)
+	isEq(t, 0, len(failures))
+	isEq(t, 10, countUsers())
+	// stop at first error, earlier iterations are committed
+	sq.MustExecSql("DELETE FROM users")
+	failures, err = sq.ExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 10, 2, produceUsers(4, 7), BatchOptions{ChunkSize: 3})
+	isErr(t, err, "iteration 4: sqinn: NOT NULL constraint failed: users.name")
+	isEq(t, 0, len(failures))
+	var batchErr *BatchError
+	isTrue(t, errors.As(err, &batchErr), "want *BatchError but have %T", err)
+	isEq(t, 4, batchErr.Iteration)
+	isEq(t, 2, len(batchErr.Params))
+	isEq(t, 5, batchErr.Params[0].Int32)
+	isEq(t, ValNull, batchErr.Params[1].Type)
+	isEq(t, 4, countUsers())
+	// continue on error, all other iterations are committed
+	sq.MustExecSql("DELETE FROM users")
+	failures, err = sq.ExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 10, 2, produceUsers(4, 7), BatchOptions{ContinueOnError: true, ChunkSize: 3})
+	isNoErr(t, err)
+	isEq(t, 2, len(failures))
+	isEq(t, 4, failures[0].Iteration)
+	isEq(t, 5, failures[0].Params[0].Int32)
+	isEq(t, "iteration 4: sqinn: NOT NULL constraint failed: users.name", failures[0].Error())
+	isEq(t, 7, failures[1].Iteration)
+	isEq(t, 8, failures[1].Params[0].Int32)
+	isEq(t, 8, countUsers())
+	// continue on error within an outer transaction
+	sq.MustExecSql("DELETE FROM users")
+	sq.MustExecSql("BEGIN")
+	failures = sq.MustExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 2000, 2, produceUsers(0, 1999), BatchOptions{ContinueOnError: true})
+	isEq(t, 2, len(failures))
+	isEq(t, 0, failures[0].Iteration)
+	isEq(t, 1999, failures[1].Iteration)
+	isEq(t, 1998, countUsers())
+	sq.MustExecSql("ROLLBACK")
+	isEq(t, 0, countUsers())
+	// errors that do not depend on the params are reported once
+	failures, err = sq.ExecBatch("INSERT INTO no_such_table (id,name) VALUES(?,?)", 10, 2, produceUsers(), BatchOptions{ContinueOnError: true, ChunkSize: 3})
+	isErr(t, err, "sqinn: no such table: no_such_table")
+	isEq(t, 0, len(failures))
+	_, err = sq.ExecBatch("INSERT INTO no_such_table (id,name) VALUES(?,?)", 10, 2, produceUsers(), BatchOptions{})
+	isErr(t, err, "sqinn: no such table: no_such_table")
+	isTrue(t, !errors.As(err, &batchErr), "want no *BatchError but have %v", err)
+	sq.MustExecSql("INSERT INTO users (id,name) VALUES(1,'User')") // no savepoint left open
+	// invalid arguments
+	isPanic(t, "invalid niterations < 0", func() {
+		sq.ExecBatch("DELETE FROM users", -1, 0, nil, BatchOptions{})
+	})
+	isPanic(t, "invalid nparams < 0", func() {
+		sq.ExecBatch("DELETE FROM users", 1, -1, nil, BatchOptions{})
+	})
+	isPanic(t, "invalid nparams > 0 && produce == nil", func() {
+		sq.ExecBatch("DELETE FROM users WHERE id=?", 1, 1, nil, BatchOptions{})
+	})
+}
+
+func TestExecBatchConcurrent(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY NOT NULL, name TEXT NOT NULL)")
+	sq.MustExecSql("CREATE TABLE logs (id INTEGER PRIMARY KEY NOT NULL)")
+	// another goroutine writes while the batch rolls back failing chunks
+	stop := make(chan struct{})
+	done := make(chan error)
+	nlogs := 0
+	go func() {
+		for {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if err := sq.ExecParams("INSERT INTO logs (id) VALUES(?)", 1, 1, []Value{Int32Value(nlogs)}); err != nil {
+				done <- err
+				return
+			}
+			nlogs++
+		}
+	}()
+	failures, err := sq.ExecBatch("INSERT INTO users (id,name) VALUES(?,?)", 2000, 2, func(iteration int, params []Value) {
+		params[0] = Int32Value(iteration + 1)
+		params[1] = StringValue("User")
+		if iteration%10 == 0 {
+			params[1] = NullValue()
+		}
+	}, BatchOptions{ContinueOnError: true, ChunkSize: 5})
+	close(stop)
+	isNoErr(t, <-done)
+	isNoErr(t, err)
+	isEq(t, 200, len(failures))
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM logs", nil, []byte{ValInt32})
+	isEq(t, nlogs, rows[0][0].Int32)
+}
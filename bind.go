@@ -0,0 +1,183 @@
+package sqinn
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// A Binder converts Go values to sqinn Values, see Bind for supported types.
+// The zero Binder is ready to use.
+type Binder struct {
+	// TimeFormat is the format for time.Time values.
+	// Default is TimeText.
+	TimeFormat TimeFormat
+
+	// Codecs holds codecs for custom Go types. If a codec is registered for
+	// the type of a Go value, it is used before any other conversion.
+	// Default is nil (no codecs).
+	Codecs *Codecs
+
+	// Custom is a hook for custom Go types. If it is not nil, it is called for
+	// each non-nil Go value before any built-in conversion takes place. If it
+	// returns ok=true, the returned Value is used, otherwise the built-in
+	// conversions are applied.
+	// Default is nil.
+	Custom func(v any) (value Value, ok bool, err error)
+}
+
+// Bind converts Go values to sqinn Values. It returns an error if
+// a Go value cannot be converted.
+func (b Binder) Bind(params []any) ([]Value, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	values := make([]Value, len(params))
+	for i, p := range params {
+		v, err := b.BindValue(p)
+		if err != nil {
+			return nil, fmt.Errorf("sqinn.Bind(): param %d: %w", i, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// BindValue converts a Go value to a sqinn Value.
+func (b Binder) BindValue(p any) (Value, error) {
+	if p == nil {
+		return NullValue(), nil
+	}
+	if b.Codecs != nil {
+		v, ok, err := b.Codecs.Encode(p)
+		if err != nil || ok {
+			return v, err
+		}
+	}
+	if b.Custom != nil {
+		v, ok, err := b.Custom(p)
+		if err != nil || ok {
+			return v, err
+		}
+	}
+	if v, ok, err := b.bindBuiltin(p); ok {
+		return v, err
+	}
+	rv := reflect.ValueOf(p)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return NullValue(), nil
+		}
+		if _, isValuer := p.(driver.Valuer); !isValuer {
+			return b.BindValue(rv.Elem().Interface())
+		}
+	}
+	switch x := p.(type) {
+	case driver.Valuer:
+		dv, err := x.Value()
+		if err != nil {
+			return Value{}, err
+		}
+		if dv == nil {
+			return NullValue(), nil
+		}
+		if v, ok, err := b.bindBuiltin(dv); ok {
+			return v, err
+		}
+		return Value{}, fmt.Errorf("wrong driver.Value type %T", dv)
+	case json.Marshaler:
+		data, err := x.MarshalJSON()
+		if err != nil {
+			return Value{}, err
+		}
+		return StringValue(string(data)), nil
+	}
+	switch rv.Kind() {
+	case reflect.Int64:
+		return Int64Value(rv.Int()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return bindInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return bindUint64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return DoubleValue(rv.Float()), nil
+	case reflect.Bool:
+		return bindBool(rv.Bool()), nil
+	case reflect.String:
+		return StringValue(rv.String()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return BlobValue(rv.Bytes()), nil
+		}
+	}
+	return Value{}, fmt.Errorf("wrong Go type %T", p)
+}
+
+// bindBuiltin binds Go types that need no reflection.
+// It returns ok=false if p is not such a type.
+func (b Binder) bindBuiltin(p any) (_ Value, ok bool, _ error) {
+	switch v := p.(type) {
+	case Value:
+		return v, true, nil
+	case int:
+		return bindInt64(int64(v)), true, nil
+	case int8:
+		return Int32Value(int(v)), true, nil
+	case int16:
+		return Int32Value(int(v)), true, nil
+	case int32:
+		return Int32Value(int(v)), true, nil
+	case int64:
+		return Int64Value(v), true, nil
+	case uint:
+		v2, err := bindUint64(uint64(v))
+		return v2, true, err
+	case uint8:
+		return Int32Value(int(v)), true, nil
+	case uint16:
+		return Int32Value(int(v)), true, nil
+	case uint32:
+		return bindInt64(int64(v)), true, nil
+	case uint64:
+		v2, err := bindUint64(v)
+		return v2, true, err
+	case float32:
+		return DoubleValue(float64(v)), true, nil
+	case float64:
+		return DoubleValue(v), true, nil
+	case bool:
+		return bindBool(v), true, nil
+	case string:
+		return StringValue(v), true, nil
+	case []byte:
+		return BlobValue(v), true, nil
+	case time.Time:
+		return TimeValue(v, b.TimeFormat), true, nil
+	}
+	return Value{}, false, nil
+}
+
+// bindInt64 binds an integer as ValInt32 if it fits into 32 bits, otherwise as ValInt64.
+func bindInt64(v int64) Value {
+	if fitsInt32(v) {
+		return Int32Value(int(v))
+	}
+	return Int64Value(v)
+}
+
+func bindUint64(v uint64) (Value, error) {
+	if v > math.MaxInt64 {
+		return Value{}, fmt.Errorf("unsigned value %d overflows int64", v)
+	}
+	return bindInt64(int64(v)), nil
+}
+
+func bindBool(v bool) Value {
+	if v {
+		return Int32Value(1)
+	}
+	return Int32Value(0)
+}
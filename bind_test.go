@@ -0,0 +1,149 @@
+package sqinn
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+type testStatus int
+
+type testPoint struct{ X, Y int }
+
+func (p testPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{p.X, p.Y})
+}
+
+type testUUID [2]uint64
+
+func TestBinder(t *testing.T) {
+	str := "hello"
+	var nilStr *string
+	var nilNullString *sql.NullString
+	tm := time.Date(2025, 8, 15, 13, 14, 15, 123456789, time.FixedZone("", 2*3600))
+	values, err := BindE([]any{
+		int8(-8),                                 // [0]
+		int16(-16),                               // [1]
+		int32(-32),                               // [2]
+		uint8(8),                                 // [3]
+		uint16(16),                               // [4]
+		uint32(32),                               // [5]
+		uint32(math.MaxUint32),                   // [6]
+		uint64(64),                               // [7]
+		uint(1 << 40),                            // [8]
+		float32(1.5),                             // [9]
+		true,                                     // [10]
+		false,                                    // [11]
+		tm,                                       // [12]
+		&str,                                     // [13]
+		nilStr,                                   // [14]
+		sql.NullString{String: "x", Valid: true}, // [15]
+		sql.NullString{},                         // [16]
+		sql.NullInt64{Int64: 7, Valid: true},     // [17]
+		sql.NullBool{Bool: true, Valid: true},    // [18]
+		nilNullString,                            // [19]
+		testPoint{1, 2},                          // [20]
+		testStatus(3),                            // [21]
+		json.RawMessage(`{"a":1}`),               // [22]
+		Int64Value(5),                            // [23]
+		&tm,                                      // [24]
+	})
+	isNoErr(t, err)
+	isEq(t, 25, len(values))
+	isEq(t, ValInt32, values[0].Type)
+	isEq(t, -8, values[0].Int32)
+	isEq(t, -16, values[1].Int32)
+	isEq(t, -32, values[2].Int32)
+	isEq(t, 8, values[3].Int32)
+	isEq(t, 16, values[4].Int32)
+	isEq(t, ValInt32, values[5].Type)
+	isEq(t, 32, values[5].Int32)
+	isEq(t, ValInt64, values[6].Type)
+	isEq(t, math.MaxUint32, values[6].Int64)
+	isEq(t, ValInt32, values[7].Type)
+	isEq(t, 64, values[7].Int32)
+	isEq(t, ValInt64, values[8].Type)
+	isEq(t, 1<<40, values[8].Int64)
+	isEq(t, ValDouble, values[9].Type)
+	isEq(t, 1.5, values[9].Double)
+	isEq(t, ValInt32, values[10].Type)
+	isEq(t, 1, values[10].Int32)
+	isEq(t, ValInt32, values[11].Type)
+	isEq(t, 0, values[11].Int32)
+	isEq(t, ValString, values[12].Type)
+	isEq(t, "2025-08-15T13:14:15.123456789+02:00", values[12].String)
+	isEq(t, ValString, values[13].Type)
+	isEq(t, "hello", values[13].String)
+	isEq(t, ValNull, values[14].Type)
+	isEq(t, ValString, values[15].Type)
+	isEq(t, "x", values[15].String)
+	isEq(t, ValNull, values[16].Type)
+	isEq(t, ValInt64, values[17].Type)
+	isEq(t, 7, values[17].Int64)
+	isEq(t, ValInt32, values[18].Type)
+	isEq(t, 1, values[18].Int32)
+	isEq(t, ValNull, values[19].Type)
+	isEq(t, ValString, values[20].Type)
+	isEq(t, "[1,2]", values[20].String)
+	isEq(t, ValInt32, values[21].Type)
+	isEq(t, 3, values[21].Int32)
+	isEq(t, ValString, values[22].Type)
+	isEq(t, `{"a":1}`, values[22].String)
+	isEq(t, ValInt64, values[23].Type)
+	isEq(t, 5, values[23].Int64)
+	isEq(t, "2025-08-15T13:14:15.123456789+02:00", values[24].String)
+	// time formats
+	values = must(Binder{TimeFormat: TimeUnix}.Bind([]any{tm}))
+	isEq(t, ValInt64, values[0].Type)
+	isEq(t, tm.Unix(), values[0].Int64)
+	values = must(Binder{TimeFormat: TimeUnixMilli}.Bind([]any{tm}))
+	isEq(t, ValInt64, values[0].Type)
+	isEq(t, tm.UnixMilli(), values[0].Int64)
+	// custom hook
+	binder := Binder{Custom: func(v any) (Value, bool, error) {
+		switch x := v.(type) {
+		case testUUID:
+			return BlobValue([]byte{byte(x[0]), byte(x[1])}), true, nil
+		case testStatus:
+			if x < 0 {
+				return Value{}, false, errors.New("negative status")
+			}
+			return StringValue("status"), true, nil
+		}
+		return Value{}, false, nil
+	}}
+	values, err = binder.Bind([]any{testUUID{1, 2}, testStatus(1), 42, &[]testUUID{{3, 4}}[0]})
+	isNoErr(t, err)
+	isEq(t, ValBlob, values[0].Type)
+	isEq(t, "\x01\x02", string(values[0].Blob))
+	isEq(t, "status", values[1].String)
+	isEq(t, 42, values[2].Int32)
+	isEq(t, "\x03\x04", string(values[3].Blob))
+	_, err = binder.Bind([]any{testStatus(-1)})
+	isErr(t, err, "sqinn.Bind(): param 0: negative status")
+	// errors
+	_, err = BindE([]any{uint64(math.MaxUint64)})
+	isErr(t, err, "sqinn.Bind(): param 0: unsigned value 18446744073709551615 overflows int64")
+	_, err = BindE([]any{1, testUUID{}})
+	isErr(t, err, "sqinn.Bind(): param 1: wrong Go type sqinn.testUUID")
+	_, err = BindE([]any{[]int{1}})
+	isErr(t, err, "sqinn.Bind(): param 0: wrong Go type []int")
+}
+
+func TestBindRoundtrip(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE items (b INTEGER, t TEXT, n TEXT)")
+	tm := time.Date(2025, 8, 15, 13, 14, 15, 123456789, time.FixedZone("", -5*3600))
+	sq.MustExecParams("INSERT INTO items (b, t, n) VALUES (?, ?, ?)", 1, 3, Bind([]any{true, tm, sql.NullString{}}))
+	rows := sq.MustQueryRows("SELECT b, t, n, unixepoch(t, 'subsec') FROM items", nil, []byte{ValInt32, ValString, ValString, ValDouble})
+	isEq(t, 1, rows[0][0].Int32)
+	isEq(t, "2025-08-15T13:14:15.123456789-05:00", rows[0][1].String)
+	isEq(t, ValNull, rows[0][2].Type)
+	isEq(t, float64(tm.UnixMilli())/1000, rows[0][3].Double)
+}
@@ -0,0 +1,461 @@
+/*
+Sqinn-gen generates Go structs and helper functions for the tables and
+views of a SQLite database, and typed methods for annotated SQL queries.
+
+Usage:
+
+	sqinn-gen [flags]
+
+The flags are:
+
+	-db path
+		The database file to introspect.
+	-migrations dir
+		A directory with .sql migration files. They are executed in
+		lexical order against an in-memory database, which is then
+		introspected. Either -db or -migrations is required.
+	-tables names
+		A comma-separated list of tables and views. Default is all.
+	-pkg name
+		The package name of the generated file. Default is "db".
+	-o file
+		The output file. Default is standard output.
+	-sqinn path
+		The path of the sqinn executable. Default is ":prebuilt:".
+	-queries dir
+		A directory with annotated .sql query files, see package queries.
+		If given, sqinn-gen generates query methods instead of table
+		structs.
+
+For each table, sqinn-gen generates a struct with one field for each column,
+a Coltypes slice, a Columns string for SELECT lists, a Scan function that
+scans a result row into the struct, a Params method, and Insert and (for
+tables with a primary key) Update functions. For views, it generates only
+the struct, Coltypes, Columns and Scan function. Nullable columns are
+pointer fields. Insert leaves out an INTEGER PRIMARY KEY column, which is
+the alias for the rowid, so that SQLite assigns it. If two tables or two
+columns of a table result in the same Go name, e.g. tables "user" and
+"users", sqinn-gen reports an error.
+
+With -queries, sqinn-gen describes each query against the database, which
+is usually a scratch database built from -migrations, and generates a
+Queries type with one method per query:
+
+	-- name: GetUser :one
+	-- params: id int64
+	SELECT id, name FROM users WHERE id = ?;
+
+becomes
+
+	func (q *Queries) GetUser(ctx context.Context, id int64) (GetUserRow, error)
+
+A :one query returns ErrNoRows if there is no row and scans only the first
+row otherwise, a :many query returns a slice, and an :exec query returns
+only an error. The default kind is :many for queries that return rows and
+:exec for others. Param types can refer to the packages time and sqinn,
+which the generated file imports. Params are bound and results scanned
+with the Options.TimeFormat of the Sqinn instance. Queries without a
+"-- params:" line take their parameters as p1, p2, ... of type any. Result
+types are taken from "-- coltypes:" or, if it is missing, from the declared
+types of the result columns; such columns are nullable pointer fields. A
+query with a single result column returns the column value instead of a
+struct.
+
+A typical usage is a go:generate directive:
+
+	//go:generate go run github.com/cvilsmeier/sqinn-go/v2/cmd/sqinn-gen -migrations migrations -pkg db -o models.go
+	//go:generate go run github.com/cvilsmeier/sqinn-go/v2/cmd/sqinn-gen -migrations migrations -queries queries -pkg db -o queries.go
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cvilsmeier/sqinn-go/v2"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "sqinn-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sqinn-gen", flag.ContinueOnError)
+	dbFlag := flags.String("db", "", "the database file")
+	migrationsFlag := flags.String("migrations", "", "a directory with .sql migration files")
+	tablesFlag := flags.String("tables", "", "comma-separated tables and views, default all")
+	pkgFlag := flags.String("pkg", "db", "the package name")
+	outFlag := flags.String("o", "", "the output file, default stdout")
+	sqinnFlag := flags.String("sqinn", "", "the sqinn executable, default :prebuilt:")
+	queriesFlag := flags.String("queries", "", "a directory with annotated .sql query files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*dbFlag == "") == (*migrationsFlag == "") {
+		return fmt.Errorf("want either -db or -migrations")
+	}
+	if *queriesFlag != "" && *tablesFlag != "" {
+		return fmt.Errorf("want either -tables or -queries")
+	}
+	sq, err := sqinn.Launch(sqinn.Options{Sqinn: *sqinnFlag, Db: *dbFlag})
+	if err != nil {
+		return err
+	}
+	defer sq.Close()
+	if *migrationsFlag != "" {
+		if err := migrate(sq, *migrationsFlag); err != nil {
+			return err
+		}
+	}
+	var src []byte
+	if *queriesFlag != "" {
+		qs, err := loadQueries(sq, *queriesFlag)
+		if err != nil {
+			return err
+		}
+		src, err = generateQueries(*pkgFlag, qs)
+		if err != nil {
+			return err
+		}
+	} else {
+		var names []string
+		if *tablesFlag != "" {
+			names = strings.Split(*tablesFlag, ",")
+		}
+		tables, err := loadTables(sq, names)
+		if err != nil {
+			return err
+		}
+		src, err = generate(*pkgFlag, tables)
+		if err != nil {
+			return err
+		}
+	}
+	if *outFlag == "" {
+		_, err = stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*outFlag, src, 0o666)
+}
+
+// migrate executes the .sql files of a directory in lexical order.
+func migrate(sq *sqinn.Sqinn, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no .sql files in %s", dir)
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := sq.ExecScript(string(data), sqinn.ScriptOptions{}); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// A table is a table or view of the database.
+type table struct {
+	name    string
+	view    bool
+	columns []column
+}
+
+// A column is a column of a table or view.
+type column struct {
+	name    string
+	decl    string // declared type, e.g. "VARCHAR(20)"
+	notNull bool
+	pk      int  // position in the primary key, starting at 1, or 0
+	rowid   bool // an INTEGER PRIMARY KEY, the alias for the rowid
+}
+
+// loadTables introspects the tables and views of the main database. If
+// names is not empty, only these tables and views are loaded.
+func loadTables(sq *sqinn.Sqinn, names []string) ([]table, error) {
+	var tables []table
+	err := sq.Query("SELECT name, type FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY name", nil,
+		[]byte{sqinn.ValString, sqinn.ValString},
+		func(row int, values []sqinn.Value) {
+			tables = append(tables, table{name: values[0].String, view: values[1].String == "view"})
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		for _, name := range names {
+			if !slices.ContainsFunc(tables, func(t table) bool { return t.name == name }) {
+				return nil, fmt.Errorf("table %q not found", name)
+			}
+		}
+		tables = slices.DeleteFunc(tables, func(t table) bool { return !slices.Contains(names, t.name) })
+	}
+	for i := range tables {
+		t := &tables[i]
+		err := sq.Query("SELECT name, type, \"notnull\", pk FROM pragma_table_info(?) ORDER BY cid", []sqinn.Value{sqinn.StringValue(t.name)},
+			[]byte{sqinn.ValString, sqinn.ValString, sqinn.ValInt32, sqinn.ValInt32},
+			func(row int, values []sqinn.Value) {
+				t.columns = append(t.columns, column{name: values[0].String, decl: values[1].String, notNull: values[2].Int32 != 0, pk: values[3].Int32})
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		// An INTEGER PRIMARY KEY is an alias for the rowid and never NULL.
+		for j, col := range t.columns {
+			if col.pk > 0 && strings.EqualFold(col.decl, "INTEGER") && t.pkCount() == 1 {
+				t.columns[j].notNull = true
+				t.columns[j].rowid = true
+			}
+		}
+	}
+	return tables, nil
+}
+
+func (t table) pkCount() int {
+	n := 0
+	for _, col := range t.columns {
+		if col.pk > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// goType returns the Go type and the sqinn coltype constant of a column,
+// derived from the column affinity, see https://www.sqlite.org/datatype3.html.
+func (c column) goType() (string, string) {
+	decl := strings.ToUpper(c.decl)
+	typ, coltype := "float64", "sqinn.ValDouble" // NUMERIC affinity
+	switch {
+	case strings.Contains(decl, "INT"):
+		typ, coltype = "int64", "sqinn.ValInt64"
+	case strings.Contains(decl, "CHAR"), strings.Contains(decl, "CLOB"), strings.Contains(decl, "TEXT"):
+		typ, coltype = "string", "sqinn.ValString"
+	case strings.Contains(decl, "BLOB"), decl == "":
+		typ, coltype = "[]byte", "sqinn.ValBlob"
+	case strings.Contains(decl, "REAL"), strings.Contains(decl, "FLOA"), strings.Contains(decl, "DOUB"):
+		typ, coltype = "float64", "sqinn.ValDouble"
+	case strings.Contains(decl, "BOOL"):
+		typ, coltype = "bool", "sqinn.ValInt32"
+	case strings.Contains(decl, "DATE"), strings.Contains(decl, "TIME"):
+		typ, coltype = "string", "sqinn.ValString"
+	}
+	if !c.notNull {
+		typ = "*" + typ
+	}
+	return typ, coltype
+}
+
+// generate generates the Go source for tables.
+func generate(pkg string, tables []table) ([]byte, error) {
+	var b bytes.Buffer
+	p := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteByte('\n')
+	}
+	p("// Code generated by sqinn-gen. DO NOT EDIT.")
+	p("")
+	p("package %s", pkg)
+	p("")
+	p("import \"github.com/cvilsmeier/sqinn-go/v2\"")
+	// Go names of different tables or columns can collide, e.g. for tables
+	// "user" and "users", so each name is checked before it is used.
+	decls := map[string]string{} // package-level Go name -> table
+	for _, t := range tables {
+		if len(t.columns) == 0 {
+			continue
+		}
+		typeName := goName(singular(t.name))
+		kind := "table"
+		if t.view {
+			kind = "view"
+		}
+		names := []string{typeName, typeName + "Coltypes", typeName + "Columns", "Scan" + typeName}
+		if !t.view {
+			names = append(names, "Insert"+typeName, "Update"+typeName)
+		}
+		for _, name := range names {
+			if other, ok := decls[name]; ok {
+				return nil, fmt.Errorf("%s %s and %s both generate Go name %s", kind, t.name, other, name)
+			}
+			decls[name] = kind + " " + t.name
+		}
+		members := map[string]string{} // field or method name -> column
+		if !t.view {
+			members["Params"] = "method Params"
+		}
+		var fields, coltypes, quoted, fieldPtrs, fieldVals []string
+		var insertQuoted, insertVals, insertMarks []string
+		for _, col := range t.columns {
+			field := goName(col.name)
+			if other, ok := members[field]; ok {
+				return nil, fmt.Errorf("%s %s: column %s and %s both generate Go name %s", kind, t.name, col.name, other, field)
+			}
+			members[field] = "column " + col.name
+			typ, coltype := col.goType()
+			fields = append(fields, fmt.Sprintf("%s %s `sqinn:%q`", field, typ, col.name))
+			coltypes = append(coltypes, coltype)
+			quoted = append(quoted, quoteIdent(col.name))
+			fieldPtrs = append(fieldPtrs, "&r."+field)
+			fieldVals = append(fieldVals, "r."+field)
+			if !col.rowid {
+				insertQuoted = append(insertQuoted, quoteIdent(col.name))
+				insertVals = append(insertVals, "r."+field)
+				insertMarks = append(insertMarks, "?")
+			}
+		}
+		p("")
+		p("// %s is a row of %s %s.", typeName, kind, t.name)
+		p("type %s struct {", typeName)
+		for _, f := range fields {
+			p("\t%s", f)
+		}
+		p("}")
+		p("")
+		p("// %sColtypes are the coltypes of the columns of %s %s.", typeName, kind, t.name)
+		p("var %sColtypes = []byte{%s}", typeName, strings.Join(coltypes, ", "))
+		p("")
+		p("// %sColumns are the columns of %s %s, for SELECT statements.", typeName, kind, t.name)
+		p("const %sColumns = %s", typeName, goString(strings.Join(quoted, ", ")))
+		p("")
+		p("// Scan%s returns the %s of a result row with %sColumns.", typeName, typeName, typeName)
+		p("func Scan%s(values []sqinn.Value) (%s, error) {", typeName, typeName)
+		p("\tvar r %s", typeName)
+		p("\terr := sqinn.Scan(values).Scan(%s)", strings.Join(fieldPtrs, ", "))
+		p("\treturn r, err")
+		p("}")
+		if t.view {
+			continue
+		}
+		p("")
+		p("// Params returns the values of r, in the order of %sColumns.", typeName)
+		p("func (r %s) Params() ([]sqinn.Value, error) {", typeName)
+		p("\treturn sqinn.BindE([]any{%s})", strings.Join(fieldVals, ", "))
+		p("}")
+		p("")
+		rowid := slices.IndexFunc(t.columns, func(col column) bool { return col.rowid })
+		if rowid < 0 {
+			p("// Insert%s inserts r into table %s.", typeName, t.name)
+		} else {
+			p("// Insert%s inserts r into table %s. It leaves out r.%s, so that SQLite", typeName, t.name, goName(t.columns[rowid].name))
+			p("// assigns the rowid.")
+		}
+		p("func Insert%s(sq *sqinn.Sqinn, r %s) error {", typeName, typeName)
+		if len(insertVals) == 0 {
+			p("\treturn sq.ExecSql(%s)", goString(fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", quoteIdent(t.name))))
+			p("}")
+		} else {
+			if rowid < 0 {
+				p("\tparams, err := r.Params()")
+			} else {
+				p("\tparams, err := sqinn.BindE([]any{%s})", strings.Join(insertVals, ", "))
+			}
+			p("\tif err != nil {")
+			p("\t\treturn err")
+			p("\t}")
+			insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(t.name), strings.Join(insertQuoted, ", "), strings.Join(insertMarks, ", "))
+			p("\treturn sq.ExecParams(%s, 1, len(params), params)", goString(insert))
+			p("}")
+		}
+		if t.pkCount() == 0 || t.pkCount() == len(t.columns) {
+			continue
+		}
+		var sets, wheres, setVals, whereVals []string
+		for _, col := range t.columns {
+			if col.pk > 0 {
+				wheres = append(wheres, quoteIdent(col.name)+" = ?")
+				whereVals = append(whereVals, "r."+goName(col.name))
+			} else {
+				sets = append(sets, quoteIdent(col.name)+" = ?")
+				setVals = append(setVals, "r."+goName(col.name))
+			}
+		}
+		update := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteIdent(t.name), strings.Join(sets, ", "), strings.Join(wheres, " AND "))
+		p("")
+		p("// Update%s updates the row of table %s that has the primary key of r.", typeName, t.name)
+		p("func Update%s(sq *sqinn.Sqinn, r %s) error {", typeName, typeName)
+		p("\tparams, err := sqinn.BindE([]any{%s})", strings.Join(append(setVals, whereVals...), ", "))
+		p("\tif err != nil {")
+		p("\t\treturn err")
+		p("\t}")
+		p("\treturn sq.ExecParams(%s, 1, len(params), params)", goString(update))
+		p("}")
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+// quoteIdent quotes a SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// goString returns s as Go string literal, preferably a raw string literal.
+func goString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// initialisms are upper-cased in Go names.
+var initialisms = []string{"ID", "URL", "URI", "UUID", "HTTP", "JSON", "API", "SQL", "HTML", "IP"}
+
+// goName converts a SQL name like "user_id" to an exported Go name like "UserID".
+func goName(name string) string {
+	var sb strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r >= 0x80)
+	}) {
+		upper := strings.ToUpper(word)
+		if slices.Contains(initialisms, upper) {
+			sb.WriteString(upper)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(word)
+		sb.WriteRune(unicode.ToUpper(r))
+		sb.WriteString(word[size:])
+	}
+	s := sb.String()
+	if s == "" || ('0' <= s[0] && s[0] <= '9') {
+		s = "X" + s
+	}
+	return s
+}
+
+// singular returns the singular of an English plural, using simple rules.
+func singular(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(name) > 4:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "us") && len(name) > 3:
+		return name[:len(name)-1]
+	}
+	return name
+}
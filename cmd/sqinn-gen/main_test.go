@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	isNoErr(t, os.Mkdir(migrations, 0o777))
+	writeFile(t, filepath.Join(migrations, "001_init.sql"), "CREATE TABLE categories (id INTEGER PRIMARY KEY, name TEXT NOT NULL, note VARCHAR(20));")
+	writeFile(t, filepath.Join(migrations, "002_view.sql"), "CREATE VIEW category_names AS SELECT name FROM categories;\nCREATE TABLE skipped (x);")
+	var stdout bytes.Buffer
+	isNoErr(t, run([]string{"-migrations", migrations, "-pkg", "models", "-tables", "categories,category_names"}, &stdout))
+	want := "// Code generated by sqinn-gen. DO NOT EDIT.\n" +
+		"\n" +
+		"package models\n" +
+		"\n" +
+		"import \"github.com/cvilsmeier/sqinn-go/v2\"\n" +
+		"\n" +
+		"// Category is a row of table categories.\n" +
+		"type Category struct {\n" +
+		"\tID   int64   `sqinn:\"id\"`\n" +
+		"\tName string  `sqinn:\"name\"`\n" +
+		"\tNote *string `sqinn:\"note\"`\n" +
+		"}\n" +
+		"\n" +
+		"// CategoryColtypes are the coltypes of the columns of table categories.\n" +
+		"var CategoryColtypes = []byte{sqinn.ValInt64, sqinn.ValString, sqinn.ValString}\n" +
+		"\n" +
+		"// CategoryColumns are the columns of table categories, for SELECT statements.\n" +
+		"const CategoryColumns = `\"id\", \"name\", \"note\"`\n" +
+		"\n" +
+		"// ScanCategory returns the Category of a result row with CategoryColumns.\n" +
+		"func ScanCategory(values []sqinn.Value) (Category, error) {\n" +
+		"\tvar r Category\n" +
+		"\terr := sqinn.Scan(values).Scan(&r.ID, &r.Name, &r.Note)\n" +
+		"\treturn r, err\n" +
+		"}\n" +
+		"\n" +
+		"// Params returns the values of r, in the order of CategoryColumns.\n" +
+		"func (r Category) Params() ([]sqinn.Value, error) {\n" +
+		"\treturn sqinn.BindE([]any{r.ID, r.Name, r.Note})\n" +
+		"}\n" +
+		"\n" +
+		"// InsertCategory inserts r into table categories. It leaves out r.ID, so that SQLite\n" +
+		"// assigns the rowid.\n" +
+		"func InsertCategory(sq *sqinn.Sqinn, r Category) error {\n" +
+		"\tparams, err := sqinn.BindE([]any{r.Name, r.Note})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\treturn sq.ExecParams(`INSERT INTO \"categories\" (\"name\", \"note\") VALUES (?, ?)`, 1, len(params), params)\n" +
+		"}\n" +
+		"\n" +
+		"// UpdateCategory updates the row of table categories that has the primary key of r.\n" +
+		"func UpdateCategory(sq *sqinn.Sqinn, r Category) error {\n" +
+		"\tparams, err := sqinn.BindE([]any{r.Name, r.Note, r.ID})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\treturn sq.ExecParams(`UPDATE \"categories\" SET \"name\" = ?, \"note\" = ? WHERE \"id\" = ?`, 1, len(params), params)\n" +
+		"}\n" +
+		"\n" +
+		"// CategoryName is a row of view category_names.\n" +
+		"type CategoryName struct {\n" +
+		"\tName *string `sqinn:\"name\"`\n" +
+		"}\n" +
+		"\n" +
+		"// CategoryNameColtypes are the coltypes of the columns of view category_names.\n" +
+		"var CategoryNameColtypes = []byte{sqinn.ValString}\n" +
+		"\n" +
+		"// CategoryNameColumns are the columns of view category_names, for SELECT statements.\n" +
+		"const CategoryNameColumns = `\"name\"`\n" +
+		"\n" +
+		"// ScanCategoryName returns the CategoryName of a result row with CategoryNameColumns.\n" +
+		"func ScanCategoryName(values []sqinn.Value) (CategoryName, error) {\n" +
+		"\tvar r CategoryName\n" +
+		"\terr := sqinn.Scan(values).Scan(&r.Name)\n" +
+		"\treturn r, err\n" +
+		"}\n"
+	isEq(t, want, stdout.String())
+	typeCheck(t, stdout.Bytes())
+	// database file and output file
+	db := filepath.Join(dir, "test.db")
+	out := filepath.Join(dir, "models.go")
+	isNoErr(t, run([]string{"-migrations", migrations, "-pkg", "x", "-o", out}, &stdout))
+	data, err := os.ReadFile(out)
+	isNoErr(t, err)
+	isTrue(t, strings.Contains(string(data), "type Skipped struct {\n\tX *[]byte `sqinn:\"x\"`\n}"), "have %s", data)
+	stdout.Reset()
+	isNoErr(t, run([]string{"-db", db}, &stdout))
+	isEq(t, "// Code generated by sqinn-gen. DO NOT EDIT.\n\npackage db\n\nimport \"github.com/cvilsmeier/sqinn-go/v2\"\n", stdout.String())
+	// errors
+	isErr(t, run(nil, &stdout), "want either -db or -migrations")
+	isErr(t, run([]string{"-db", db, "-migrations", migrations}, &stdout), "want either -db or -migrations")
+	isErr(t, run([]string{"-migrations", dir + "/nothing"}, &stdout), "no .sql files in "+dir+"/nothing")
+	isErr(t, run([]string{"-migrations", migrations, "-tables", "nope"}, &stdout), `table "nope" not found`)
+	writeFile(t, filepath.Join(migrations, "003_bad.sql"), "CREATE TABLE t (a);\nCREATE TABLE t (b);")
+	err = run([]string{"-migrations", migrations}, &stdout)
+	isErr(t, err, filepath.Join(migrations, "003_bad.sql")+": statement 2 (line 2, column 1): sqinn: table t already exists")
+}
+
+func TestGenerateInsert(t *testing.T) {
+	id := column{name: "id", decl: "INTEGER", notNull: true, pk: 1, rowid: true}
+	code := column{name: "code", decl: "TEXT", notNull: true, pk: 1}
+	name := column{name: "name", decl: "TEXT"}
+	src, err := generate("db", []table{
+		{name: "counters", columns: []column{id}},
+		{name: "codes", columns: []column{code, name}},
+	})
+	isNoErr(t, err)
+	isTrue(t, strings.Contains(string(src), "func InsertCounter(sq *sqinn.Sqinn, r Counter) error {\n\treturn sq.ExecSql(`INSERT INTO \"counters\" DEFAULT VALUES`)\n}"), "have %s", src)
+	isTrue(t, strings.Contains(string(src), "\tparams, err := r.Params()\n"), "have %s", src)
+	isTrue(t, strings.Contains(string(src), "`INSERT INTO \"codes\" (\"code\", \"name\") VALUES (?, ?)`"), "have %s", src)
+	typeCheck(t, src)
+}
+
+func TestGenerateNames(t *testing.T) {
+	a := column{name: "a", decl: "TEXT"}
+	_, err := generate("db", []table{{name: "user", columns: []column{a}}, {name: "users", columns: []column{a}}})
+	isErr(t, err, "table users and table user both generate Go name User")
+	_, err = generate("db", []table{{name: "user", columns: []column{a}}, {name: "user_columns", view: true, columns: []column{a}}})
+	isNoErr(t, err)
+	_, err = generate("db", []table{{name: "scan_users", columns: []column{a}}, {name: "users", view: true, columns: []column{a}}})
+	isErr(t, err, "view users and table scan_users both generate Go name ScanUser")
+	_, err = generate("db", []table{{name: "users", columns: []column{{name: "user_id"}, {name: "user id"}}}})
+	isErr(t, err, "table users: column user id and column user_id both generate Go name UserID")
+	_, err = generate("db", []table{{name: "users", columns: []column{{name: "params"}}}})
+	isErr(t, err, "table users: column params and method Params both generate Go name Params")
+	_, err = generate("db", []table{{name: "users", view: true, columns: []column{{name: "params"}}}})
+	isNoErr(t, err)
+}
+
+func TestGoName(t *testing.T) {
+	isEq(t, "UserID", goName("user_id"))
+	isEq(t, "CreatedAt", goName("createdAt"))
+	isEq(t, "HomepageURL", goName("homepage url"))
+	isEq(t, "X2fa", goName("2fa"))
+	isEq(t, "Ärger", goName("ärger"))
+	isEq(t, "X", goName("___"))
+}
+
+func TestSingular(t *testing.T) {
+	isEq(t, "user", singular("users"))
+	isEq(t, "category", singular("categories"))
+	isEq(t, "box", singular("boxes"))
+	isEq(t, "class", singular("classes"))
+	isEq(t, "status", singular("status"))
+	isEq(t, "address", singular("address"))
+	isEq(t, "bus", singular("bus"))
+	isEq(t, "person", singular("person"))
+}
+
+// typeCheck vets src, a generated file, in a temporary module that uses
+// the sqinn-go module of this repository.
+func typeCheck(t *testing.T, src []byte) {
+	t.Helper()
+	root, err := filepath.Abs("../..")
+	isNoErr(t, err)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module check\n\n"+
+		"go 1.23\n\n"+
+		"require github.com/cvilsmeier/sqinn-go/v2 v2.0.0\n\n"+
+		"replace github.com/cvilsmeier/sqinn-go/v2 => "+root+"\n")
+	writeFile(t, filepath.Join(dir, "gen.go"), string(src))
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	isTrue(t, err == nil, "go vet: %v\n%s\n%s", err, out, src)
+}
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	isNoErr(t, os.WriteFile(name, []byte(data), 0o666))
+}
+
+// assertion library
+
+func isTrue(t *testing.T, condition bool, format string, args ...any) {
+	t.Helper()
+	if !condition {
+		t.Fatalf(format, args...)
+	}
+}
+
+func isNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("want no err but have %s", err)
+	}
+}
+
+func isErr(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("want err but have nil")
+	} else if err.Error() != want {
+		t.Fatalf("want err %q but have %q", want, err.Error())
+	}
+}
+
+func isEq[T comparable](t *testing.T, want, have T) {
+	t.Helper()
+	if want != have {
+		t.Fatalf("want %T(%v) but have %T(%v)", want, want, have, have)
+	}
+}
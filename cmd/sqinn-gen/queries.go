@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cvilsmeier/sqinn-go/v2"
+	"github.com/cvilsmeier/sqinn-go/v2/queries"
+)
+
+// A query is an annotated query, described against the database.
+type query struct {
+	*queries.Query
+	method  string  // the Go method name
+	kind    string  // "one", "many" or "exec"
+	params  []param // the method parameters
+	fields  []field // the result fields
+	resType string  // the result type, a struct name or a scalar type
+}
+
+// A param is a method parameter.
+type param struct {
+	name string
+	typ  string
+	pkgs []string // the packages that typ refers to, e.g. "time"
+}
+
+// A field is a field of a result struct.
+type field struct {
+	name    string
+	column  string
+	typ     string
+	coltype string
+}
+
+// loadQueries parses the .sql files of a directory and describes the
+// queries against sq.
+func loadQueries(sq *sqinn.Sqinn, dir string) ([]query, error) {
+	set, err := queries.Parse(os.DirFS(dir), "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(set.Queries()) == 0 {
+		return nil, fmt.Errorf("no queries in %s", dir)
+	}
+	if err := set.Validate(sq); err != nil {
+		return nil, err
+	}
+	var qs []query
+	methods := map[string]bool{}
+	for _, q := range set.Queries() {
+		desc, err := sq.Describe(q.Sql)
+		if err != nil {
+			return nil, err
+		}
+		gq, err := newQuery(q, desc)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: query %s: %w", q.File, q.Line, q.Name, err)
+		}
+		if methods[gq.method] {
+			return nil, fmt.Errorf("%s:%d: query %s: duplicate method name %s", q.File, q.Line, q.Name, gq.method)
+		}
+		methods[gq.method] = true
+		qs = append(qs, gq)
+	}
+	return qs, nil
+}
+
+// reservedNames are the local and package names of generated methods, they
+// cannot be used as param names.
+var reservedNames = []string{"q", "ctx", "params", "rows", "r", "err", "scanErr", "found", "sqinn", "context", "errors", "time"}
+
+// importedPackages are the packages that param types can refer to, the
+// generated file imports them.
+var importedPackages = []string{"sqinn", "time"}
+
+// newQuery determines the method signature of a query.
+func newQuery(q *queries.Query, desc *sqinn.Description) (query, error) {
+	gq := query{Query: q, method: goName(q.Name), kind: q.Kind}
+	if gq.kind == "" {
+		gq.kind = "exec"
+		if desc.Columns != nil {
+			gq.kind = "many"
+		}
+	}
+	if gq.kind == "exec" && len(q.Coltypes) > 0 {
+		return query{}, fmt.Errorf("kind :exec with coltypes")
+	}
+	if gq.kind != "exec" && desc.Columns == nil {
+		return query{}, fmt.Errorf("kind :%s but query returns no rows", gq.kind)
+	}
+	if q.Params != nil {
+		for _, p := range q.Params {
+			if !token.IsIdentifier(p.Name) || slices.Contains(reservedNames, p.Name) {
+				return query{}, fmt.Errorf("invalid param name %q", p.Name)
+			}
+			pkgs, err := typePackages(p.Type)
+			if err != nil {
+				return query{}, fmt.Errorf("param %s: %w", p.Name, err)
+			}
+			gq.params = append(gq.params, param{p.Name, p.Type, pkgs})
+		}
+	} else {
+		for i := range desc.NumParams {
+			gq.params = append(gq.params, param{name: fmt.Sprintf("p%d", i+1), typ: "any"})
+		}
+	}
+	if gq.kind == "exec" {
+		return gq, nil
+	}
+	names := map[string]bool{}
+	for i, col := range desc.Columns {
+		f := field{name: goName(col.Name), column: col.Name}
+		if col.Name == "" || names[f.name] {
+			f.name = fmt.Sprintf("C%d", i+1)
+		}
+		names[f.name] = true
+		if len(q.Coltypes) > 0 {
+			f.typ, f.coltype = coltypeGoType(q.Coltypes[i], q.Nullable[i])
+		} else {
+			if col.Decl == "" {
+				return query{}, fmt.Errorf("cannot infer type of column %d (%s), use -- coltypes", i+1, col.Name)
+			}
+			// declared types do not tell whether a result column can be NULL
+			f.typ, f.coltype = column{decl: col.Decl}.goType()
+		}
+		gq.fields = append(gq.fields, f)
+	}
+	gq.resType = gq.method + "Row"
+	if len(gq.fields) == 1 {
+		gq.resType = gq.fields[0].typ
+	}
+	return gq, nil
+}
+
+// typePackages returns the packages that a Go type expression refers to. It
+// fails if typ is not a type expression or refers to a package that is not
+// in importedPackages.
+func typePackages(typ string) ([]string, error) {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %q", typ)
+	}
+	var pkgs []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && !slices.Contains(pkgs, id.Name) {
+				pkgs = append(pkgs, id.Name)
+			}
+		}
+		return true
+	})
+	for _, pkg := range pkgs {
+		if !slices.Contains(importedPackages, pkg) {
+			return nil, fmt.Errorf("type %s refers to package %s, want one of %s", typ, pkg, strings.Join(importedPackages, ", "))
+		}
+	}
+	return pkgs, nil
+}
+
+// coltypeGoType returns the Go type and the sqinn coltype constant of a coltype.
+func coltypeGoType(coltype byte, nullable bool) (string, string) {
+	var typ, name string
+	switch coltype {
+	case sqinn.ValInt32:
+		typ, name = "int", "sqinn.ValInt32"
+	case sqinn.ValInt64:
+		typ, name = "int64", "sqinn.ValInt64"
+	case sqinn.ValDouble:
+		typ, name = "float64", "sqinn.ValDouble"
+	case sqinn.ValString:
+		typ, name = "string", "sqinn.ValString"
+	default:
+		typ, name = "[]byte", "sqinn.ValBlob"
+	}
+	if nullable {
+		typ = "*" + typ
+	}
+	return typ, name
+}
+
+// generateQueries generates the Go source for queries.
+func generateQueries(pkg string, qs []query) ([]byte, error) {
+	var b bytes.Buffer
+	p := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteByte('\n')
+	}
+	usesTime := slices.ContainsFunc(qs, func(q query) bool {
+		return slices.ContainsFunc(q.params, func(p param) bool { return slices.Contains(p.pkgs, "time") })
+	})
+	usesErrors := slices.ContainsFunc(qs, func(q query) bool { return q.kind == "one" })
+	p("// Code generated by sqinn-gen. DO NOT EDIT.")
+	p("")
+	p("package %s", pkg)
+	p("")
+	p("import (")
+	p("\t\"context\"")
+	if usesErrors {
+		p("\t\"errors\"")
+	}
+	if usesTime {
+		p("\t\"time\"")
+	}
+	p("")
+	p("\t\"github.com/cvilsmeier/sqinn-go/v2\"")
+	p(")")
+	if usesErrors {
+		p("")
+		p("// ErrNoRows is returned by queries that return one row if there is no row.")
+		p("var ErrNoRows = errors.New(\"no rows in result set\")")
+	}
+	p("")
+	p("// Queries executes the queries.")
+	p("type Queries struct {")
+	p("\tsq *sqinn.Sqinn")
+	p("}")
+	p("")
+	p("// New returns Queries that execute on sq.")
+	p("func New(sq *sqinn.Sqinn) *Queries {")
+	p("\treturn &Queries{sq}")
+	p("}")
+	for _, q := range qs {
+		local := lowerFirst(q.method)
+		var args, names []string
+		for _, prm := range q.params {
+			args = append(args, prm.name+" "+prm.typ)
+			names = append(names, prm.name)
+		}
+		var coltypes, ptrs []string
+		for _, f := range q.fields {
+			coltypes = append(coltypes, f.coltype)
+			ptrs = append(ptrs, "&r."+f.name)
+		}
+		if len(q.fields) == 1 {
+			ptrs = []string{"&r"}
+		}
+		p("")
+		p("const %sSql = %s", local, goString(q.Sql))
+		if q.kind != "exec" {
+			p("")
+			p("var %sColtypes = []byte{%s}", local, strings.Join(coltypes, ", "))
+		}
+		if len(q.fields) > 1 {
+			p("")
+			p("// %s is a result row of %s.", q.resType, q.method)
+			p("type %s struct {", q.resType)
+			for _, f := range q.fields {
+				p("\t%s %s `sqinn:%q`", f.name, f.typ, f.column)
+			}
+			p("}")
+		}
+		p("")
+		p("// %s executes query %s of %s.", q.method, q.Name, q.File)
+		switch q.kind {
+		case "one":
+			p("func (q *Queries) %s(%s) (%s, error) {", q.method, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), q.resType)
+			p("\tvar r %s", q.resType)
+			p("\tif err := ctx.Err(); err != nil {")
+			p("\t\treturn r, err")
+			p("\t}")
+			p("\tparams, err := q.sq.BindE([]any{%s})", strings.Join(names, ", "))
+			p("\tif err != nil {")
+			p("\t\treturn r, err")
+			p("\t}")
+			p("\tfound := false")
+			p("\tvar scanErr error")
+			p("\terr = q.sq.Query(%sSql, params, %sColtypes, func(_ int, values []sqinn.Value) {", local, local)
+			p("\t\tif !found {")
+			p("\t\t\tfound = true")
+			p("\t\t\tscanErr = q.sq.Scan(values).Scan(%s)", strings.Join(ptrs, ", "))
+			p("\t\t}")
+			p("\t})")
+			p("\tif err != nil {")
+			p("\t\treturn r, err")
+			p("\t}")
+			p("\tif !found {")
+			p("\t\treturn r, ErrNoRows")
+			p("\t}")
+			p("\treturn r, scanErr")
+			p("}")
+		case "many":
+			p("func (q *Queries) %s(%s) ([]%s, error) {", q.method, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), q.resType)
+			p("\tif err := ctx.Err(); err != nil {")
+			p("\t\treturn nil, err")
+			p("\t}")
+			p("\tparams, err := q.sq.BindE([]any{%s})", strings.Join(names, ", "))
+			p("\tif err != nil {")
+			p("\t\treturn nil, err")
+			p("\t}")
+			p("\tvar rows []%s", q.resType)
+			p("\tvar scanErr error")
+			p("\terr = q.sq.Query(%sSql, params, %sColtypes, func(_ int, values []sqinn.Value) {", local, local)
+			p("\t\tvar r %s", q.resType)
+			p("\t\tif scanErr == nil {")
+			p("\t\t\tscanErr = q.sq.Scan(values).Scan(%s)", strings.Join(ptrs, ", "))
+			p("\t\t\trows = append(rows, r)")
+			p("\t\t}")
+			p("\t})")
+			p("\tif err != nil {")
+			p("\t\treturn nil, err")
+			p("\t}")
+			p("\tif scanErr != nil {")
+			p("\t\treturn nil, scanErr")
+			p("\t}")
+			p("\treturn rows, nil")
+			p("}")
+		default:
+			p("func (q *Queries) %s(%s) error {", q.method, strings.Join(append([]string{"ctx context.Context"}, args...), ", "))
+			p("\tif err := ctx.Err(); err != nil {")
+			p("\t\treturn err")
+			p("\t}")
+			p("\tparams, err := q.sq.BindE([]any{%s})", strings.Join(names, ", "))
+			p("\tif err != nil {")
+			p("\t\treturn err")
+			p("\t}")
+			p("\treturn q.sq.ExecParams(%sSql, 1, len(params), params)", local)
+			p("}")
+		}
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+// lowerFirst returns s with its first rune in lower case.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunQueries(t *testing.T) {
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	isNoErr(t, os.Mkdir(migrations, 0o777))
+	writeFile(t, filepath.Join(migrations, "001_init.sql"), "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")
+	queries := filepath.Join(dir, "queries")
+	isNoErr(t, os.Mkdir(queries, 0o777))
+	writeFile(t, filepath.Join(queries, "users.sql"), "-- name: GetUser :one\n"+
+		"-- coltypes: int64, string?\n"+
+		"-- params: id int64\n"+
+		"SELECT id, name FROM users WHERE id = ?;\n"+
+		"\n"+
+		"-- name: ListNames\n"+
+		"SELECT name FROM users ORDER BY name;\n"+
+		"\n"+
+		"-- name: delete_user\n"+
+		"DELETE FROM users WHERE id = ?;\n")
+	var stdout bytes.Buffer
+	isNoErr(t, run([]string{"-migrations", migrations, "-queries", queries, "-pkg", "models"}, &stdout))
+	want := "// Code generated by sqinn-gen. DO NOT EDIT.\n" +
+		"\n" +
+		"package models\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"context\"\n" +
+		"\t\"errors\"\n" +
+		"\n" +
+		"\t\"github.com/cvilsmeier/sqinn-go/v2\"\n" +
+		")\n" +
+		"\n" +
+		"// ErrNoRows is returned by queries that return one row if there is no row.\n" +
+		"var ErrNoRows = errors.New(\"no rows in result set\")\n" +
+		"\n" +
+		"// Queries executes the queries.\n" +
+		"type Queries struct {\n" +
+		"\tsq *sqinn.Sqinn\n" +
+		"}\n" +
+		"\n" +
+		"// New returns Queries that execute on sq.\n" +
+		"func New(sq *sqinn.Sqinn) *Queries {\n" +
+		"\treturn &Queries{sq}\n" +
+		"}\n" +
+		"\n" +
+		"const getUserSql = `SELECT id, name FROM users WHERE id = ?`\n" +
+		"\n" +
+		"var getUserColtypes = []byte{sqinn.ValInt64, sqinn.ValString}\n" +
+		"\n" +
+		"// GetUserRow is a result row of GetUser.\n" +
+		"type GetUserRow struct {\n" +
+		"\tID   int64   `sqinn:\"id\"`\n" +
+		"\tName *string `sqinn:\"name\"`\n" +
+		"}\n" +
+		"\n" +
+		"// GetUser executes query GetUser of users.sql.\n" +
+		"func (q *Queries) GetUser(ctx context.Context, id int64) (GetUserRow, error) {\n" +
+		"\tvar r GetUserRow\n" +
+		"\tif err := ctx.Err(); err != nil {\n" +
+		"\t\treturn r, err\n" +
+		"\t}\n" +
+		"\tparams, err := q.sq.BindE([]any{id})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn r, err\n" +
+		"\t}\n" +
+		"\tfound := false\n" +
+		"\tvar scanErr error\n" +
+		"\terr = q.sq.Query(getUserSql, params, getUserColtypes, func(_ int, values []sqinn.Value) {\n" +
+		"\t\tif !found {\n" +
+		"\t\t\tfound = true\n" +
+		"\t\t\tscanErr = q.sq.Scan(values).Scan(&r.ID, &r.Name)\n" +
+		"\t\t}\n" +
+		"\t})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn r, err\n" +
+		"\t}\n" +
+		"\tif !found {\n" +
+		"\t\treturn r, ErrNoRows\n" +
+		"\t}\n" +
+		"\treturn r, scanErr\n" +
+		"}\n" +
+		"\n" +
+		"const listNamesSql = `SELECT name FROM users ORDER BY name`\n" +
+		"\n" +
+		"var listNamesColtypes = []byte{sqinn.ValString}\n" +
+		"\n" +
+		"// ListNames executes query ListNames of users.sql.\n" +
+		"func (q *Queries) ListNames(ctx context.Context) ([]*string, error) {\n" +
+		"\tif err := ctx.Err(); err != nil {\n" +
+		"\t\treturn nil, err\n" +
+		"\t}\n" +
+		"\tparams, err := q.sq.BindE([]any{})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn nil, err\n" +
+		"\t}\n" +
+		"\tvar rows []*string\n" +
+		"\tvar scanErr error\n" +
+		"\terr = q.sq.Query(listNamesSql, params, listNamesColtypes, func(_ int, values []sqinn.Value) {\n" +
+		"\t\tvar r *string\n" +
+		"\t\tif scanErr == nil {\n" +
+		"\t\t\tscanErr = q.sq.Scan(values).Scan(&r)\n" +
+		"\t\t\trows = append(rows, r)\n" +
+		"\t\t}\n" +
+		"\t})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn nil, err\n" +
+		"\t}\n" +
+		"\tif scanErr != nil {\n" +
+		"\t\treturn nil, scanErr\n" +
+		"\t}\n" +
+		"\treturn rows, nil\n" +
+		"}\n" +
+		"\n" +
+		"const deleteUserSql = `DELETE FROM users WHERE id = ?`\n" +
+		"\n" +
+		"// DeleteUser executes query delete_user of users.sql.\n" +
+		"func (q *Queries) DeleteUser(ctx context.Context, p1 any) error {\n" +
+		"\tif err := ctx.Err(); err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\tparams, err := q.sq.BindE([]any{p1})\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn err\n" +
+		"\t}\n" +
+		"\treturn q.sq.ExecParams(deleteUserSql, 1, len(params), params)\n" +
+		"}\n"
+	isEq(t, want, stdout.String())
+	typeCheck(t, stdout.Bytes())
+	// param types
+	writeFile(t, filepath.Join(queries, "users.sql"), "-- name: CountSince :one\n"+
+		"-- coltypes: int64\n"+
+		"-- params: since time.Time, until *time.Time, v sqinn.Value\n"+
+		"SELECT COUNT(*) FROM users WHERE ? < ? AND ? IS NOT NULL;\n")
+	stdout.Reset()
+	isNoErr(t, run([]string{"-migrations", migrations, "-queries", queries}, &stdout))
+	isTrue(t, strings.Contains(stdout.String(), "\t\"time\"\n"), "have %s", stdout.String())
+	typeCheck(t, stdout.Bytes())
+	// errors
+	isErr(t, run([]string{"-migrations", migrations, "-queries", queries, "-tables", "users"}, &stdout), "want either -tables or -queries")
+	isErr(t, run([]string{"-migrations", migrations, "-queries", dir}, &stdout), "no queries in "+dir)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"-- name: A\nSELECT nope FROM users", "x.sql:1: query A: sqinn: no such column: nope"},
+		{"-- name: A :one\nDELETE FROM users", "x.sql:1: query A: kind :one but query returns no rows"},
+		{"-- name: A :exec\n-- coltypes: int64\nSELECT id FROM users", "x.sql:1: query A: kind :exec with coltypes"},
+		{"-- name: A\n-- params: ctx int64\nDELETE FROM users WHERE id = ?", `x.sql:1: query A: invalid param name "ctx"`},
+		{"-- name: A\nSELECT COUNT(*) FROM users", "x.sql:1: query A: cannot infer type of column 1 (COUNT(*)), use -- coltypes"},
+		{"-- name: a\nDELETE FROM users\n-- name: A\nDELETE FROM users", "x.sql:3: query A: duplicate method name A"},
+		{"-- name: A\n-- params: id uuid.UUID\nDELETE FROM users WHERE id = ?", "x.sql:1: query A: param id: type uuid.UUID refers to package uuid, want one of sqinn, time"},
+		{"-- name: A\n-- params: id int64)\nDELETE FROM users WHERE id = ?", `x.sql:1: query A: param id: invalid type "int64)"`},
+	}
+	for _, tt := range tests {
+		writeFile(t, filepath.Join(queries, "users.sql"), "")
+		writeFile(t, filepath.Join(queries, "x.sql"), tt.text)
+		isErr(t, run([]string{"-migrations", migrations, "-queries", queries}, &stdout), tt.want)
+	}
+}
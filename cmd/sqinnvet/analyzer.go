@@ -0,0 +1,150 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var analyzer = &analysis.Analyzer{
+	Name:     "sqinnvet",
+	Doc:      "check parameter counts and coltypes of sqinn calls with constant SQL",
+	URL:      "https://pkg.go.dev/github.com/cvilsmeier/sqinn-go/v2/cmd/sqinnvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const sqinnPath = "github.com/cvilsmeier/sqinn-go/v2"
+
+// argPos holds the positions of the arguments of a sqinn method, or -1.
+type argPos struct {
+	niterations int
+	nparams     int
+	params      int
+	coltypes    int
+}
+
+// methods are the checked methods of *sqinn.Sqinn. The sql argument is
+// always the first one.
+var methods = map[string]argPos{
+	"Exec":       {niterations: 1, nparams: 2, params: -1, coltypes: -1},
+	"ExecParams": {niterations: 1, nparams: 2, params: 3, coltypes: -1},
+	"Query":      {niterations: -1, nparams: -1, params: 1, coltypes: 2},
+	"QueryRows":  {niterations: -1, nparams: -1, params: 1, coltypes: 2},
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, ok := sqinnMethod(pass.TypesInfo, call)
+		if !ok {
+			return
+		}
+		pos := methods[strings.TrimPrefix(name, "Must")]
+		sql, ok := constString(pass.TypesInfo, call.Args[0])
+		if !ok {
+			return
+		}
+		nsql := countParams(sql)
+		if pos.nparams >= 0 {
+			if nparams, ok := constInt(pass.TypesInfo, call.Args[pos.nparams]); ok && nparams != nsql {
+				pass.Reportf(call.Args[pos.nparams].Pos(), "%s: nparams is %d but SQL has %d parameters", name, nparams, nsql)
+			}
+		}
+		if pos.params >= 0 {
+			nvalues, ok := sliceLen(pass.TypesInfo, call.Args[pos.params])
+			if ok && pos.nparams < 0 && nvalues != nsql {
+				pass.Reportf(call.Args[pos.params].Pos(), "%s: have %d params but SQL has %d parameters", name, nvalues, nsql)
+			}
+			if ok && pos.nparams >= 0 {
+				niterations, ok1 := constInt(pass.TypesInfo, call.Args[pos.niterations])
+				nparams, ok2 := constInt(pass.TypesInfo, call.Args[pos.nparams])
+				if ok1 && ok2 && nvalues != niterations*nparams {
+					pass.Reportf(call.Args[pos.params].Pos(), "%s: have %d params but niterations*nparams is %d", name, nvalues, niterations*nparams)
+				}
+			}
+		}
+		if pos.coltypes >= 0 {
+			ncoltypes, ok := sliceLen(pass.TypesInfo, call.Args[pos.coltypes])
+			ncols, known := countColumns(sql)
+			switch {
+			case !ok || !known || ncoltypes == ncols:
+			case ncols == 0:
+				pass.Reportf(call.Args[pos.coltypes].Pos(), "%s: have %d coltypes but SQL returns no rows", name, ncoltypes)
+			default:
+				pass.Reportf(call.Args[pos.coltypes].Pos(), "%s: have %d coltypes but SQL returns %d columns", name, ncoltypes, ncols)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// sqinnMethod returns the name of the checked *sqinn.Sqinn method that
+// call calls, if any.
+func sqinnMethod(info *types.Info, call *ast.CallExpr) (string, bool) {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok {
+		return "", false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "", false
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Name() != "Sqinn" || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != sqinnPath {
+		return "", false
+	}
+	if _, ok := methods[strings.TrimPrefix(fn.Name(), "Must")]; !ok {
+		return "", false
+	}
+	return fn.Name(), true
+}
+
+// constString returns the value of a constant string expression.
+func constString(info *types.Info, expr ast.Expr) (string, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// constInt returns the value of a constant integer expression.
+func constInt(info *types.Info, expr ast.Expr) (int, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	i, ok := constant.Int64Val(tv.Value)
+	return int(i), ok
+}
+
+// sliceLen returns the length of nil or a slice literal without keyed
+// elements.
+func sliceLen(info *types.Info, expr ast.Expr) (int, bool) {
+	expr = ast.Unparen(expr)
+	if tv, ok := info.Types[expr]; ok && tv.IsNil() {
+		return 0, true
+	}
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return 0, false
+	}
+	for _, elt := range lit.Elts {
+		if _, ok := elt.(*ast.KeyValueExpr); ok {
+			return 0, false
+		}
+	}
+	return len(lit.Elts), true
+}
@@ -0,0 +1,39 @@
+/*
+Sqinnvet checks calls to sqinn-go for mismatches between SQL and its
+parameters and result columns.
+
+Usage:
+
+	sqinnvet [packages]
+
+or as a vet tool:
+
+	go vet -vettool=$(which sqinnvet) [packages]
+
+Sqinnvet finds calls to the Exec, ExecParams, Query and QueryRows methods
+of *sqinn.Sqinn, and their Must variants, that have a constant SQL string.
+It parses the first statement of the SQL and reports
+
+  - an nparams argument that is not the number of parameters in the SQL,
+  - a params slice literal whose length is not the number of parameters
+    in the SQL (Query and QueryRows) or not niterations*nparams
+    (ExecParams),
+  - a coltypes slice literal whose length is not the number of result
+    columns of the SQL, or that is not empty for SQL that returns no rows.
+
+Parameters are counted like SQLite counts them: ?NNN has index NNN, ? has
+the largest index so far plus one, and repeated named parameters like :id
+share one index. Result columns cannot be counted for SELECT * and for
+statements like PRAGMA, such calls are not checked.
+
+Install it with
+
+	go install github.com/cvilsmeier/sqinn-go/v2/cmd/sqinnvet@latest
+*/
+package main
+
+import "golang.org/x/tools/go/analysis/singlechecker"
+
+func main() {
+	singlechecker.Main(analyzer)
+}
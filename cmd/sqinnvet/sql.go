@@ -0,0 +1,307 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the kind of a SQL token.
+type tokenKind int
+
+const (
+	tokSpace     tokenKind = iota // whitespace
+	tokComment                    // "-- comment" or "/* comment */"
+	tokString                     // 'string' or X'blob'
+	tokIdent                      // keyword or identifier, also "quoted", [quoted] and `quoted`
+	tokNumber                     // numeric literal
+	tokParam                      // parameter: ?, ?NNN, :AAA, @AAA or $AAA
+	tokSemicolon                  // ;
+	tokOther                      // operators and punctuation
+)
+
+// A token is a SQL token.
+type token struct {
+	kind tokenKind
+	pos  int    // byte offset in the SQL text
+	text string // token text
+}
+
+// A lexer splits SQL text into tokens. It follows the SQLite tokenizer
+// closely enough to find string literals, comments, identifiers and
+// parameters, but it does not validate the SQL. Unterminated
+// strings and comments extend to the end of the SQL text.
+//
+// It is a copy of the lexer of package sqinn, which does not export it.
+type lexer struct {
+	sql string
+	pos int
+}
+
+// next returns the next token, or false at the end of the SQL text.
+func (l *lexer) next() (token, bool) {
+	sql := l.sql
+	start := l.pos
+	if start >= len(sql) {
+		return token{}, false
+	}
+	kind := tokOther
+	i := start
+	c := sql[i]
+	switch {
+	case isSpace(c):
+		kind = tokSpace
+		for i < len(sql) && isSpace(sql[i]) {
+			i++
+		}
+	case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+		kind = tokComment
+		for i < len(sql) && sql[i] != '\n' {
+			i++
+		}
+	case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+		kind = tokComment
+		i += 2
+		for i < len(sql) && !(sql[i] == '*' && i+1 < len(sql) && sql[i+1] == '/') {
+			i++
+		}
+		i = min(i+2, len(sql))
+	case c == '\'':
+		kind = tokString
+		i = skipQuoted(sql, i, '\'')
+	case (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+		kind = tokString
+		i = skipQuoted(sql, i+1, '\'')
+	case c == '"' || c == '`':
+		kind = tokIdent
+		i = skipQuoted(sql, i, c)
+	case c == '[':
+		kind = tokIdent
+		for i < len(sql) && sql[i] != ']' {
+			i++
+		}
+		i = min(i+1, len(sql))
+	case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+		kind = tokNumber
+		hex := c == '0' && i+1 < len(sql) && (sql[i+1] == 'x' || sql[i+1] == 'X')
+		for i < len(sql) && (isIdentChar(sql[i]) || sql[i] == '.' ||
+			(!hex && (sql[i] == '+' || sql[i] == '-') && (sql[i-1] == 'e' || sql[i-1] == 'E'))) {
+			i++
+		}
+	case isIdentChar(c) && c != '$':
+		kind = tokIdent
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+	case c == '?':
+		kind = tokParam
+		i++
+		for i < len(sql) && isDigit(sql[i]) {
+			i++
+		}
+	case (c == ':' || c == '@' || c == '$') && i+1 < len(sql) && isIdentChar(sql[i+1]):
+		kind = tokParam
+		i++
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+	case c == ';':
+		kind = tokSemicolon
+		i++
+	default:
+		i++
+	}
+	l.pos = i
+	return token{kind, start, sql[start:i]}, true
+}
+
+// isSignificant reports whether a token is neither whitespace nor a comment.
+func isSignificant(tok token) bool {
+	return tok.kind != tokSpace && tok.kind != tokComment
+}
+
+// skipQuoted skips a quoted string that starts at sql[i] with quote
+// character q. Doubled quote characters are escapes. It returns
+// the position after the closing quote.
+func skipQuoted(sql string, i int, q byte) int {
+	i++
+	for i < len(sql) {
+		if sql[i] == q {
+			if i+1 < len(sql) && sql[i+1] == q {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) || c == '_' || c == '$' || c >= 0x80
+}
+
+// statementTokens returns the significant tokens of the first statement
+// in sql, without the terminating semicolon.
+func statementTokens(sql string) []token {
+	var tokens []token
+	lex := lexer{sql: sql}
+	for {
+		tok, ok := lex.next()
+		if !ok || tok.kind == tokSemicolon {
+			return tokens
+		}
+		if isSignificant(tok) {
+			tokens = append(tokens, tok)
+		}
+	}
+}
+
+// keyword returns the upper case text of an unquoted identifier token,
+// or "" for other tokens.
+func keyword(tok token) string {
+	if tok.kind != tokIdent || !isIdentChar(tok.text[0]) {
+		return ""
+	}
+	return strings.ToUpper(tok.text)
+}
+
+// countParams returns the number of parameters of the first statement in
+// sql, like sqlite3_bind_parameter_count. It is the largest parameter
+// index: ? takes the largest index so far plus one, ?NNN takes index NNN,
+// and a named parameter takes the index of its first occurrence.
+func countParams(sql string) int {
+	n := 0
+	named := make(map[string]bool)
+	for _, tok := range statementTokens(sql) {
+		if tok.kind != tokParam {
+			continue
+		}
+		switch {
+		case tok.text == "?":
+			n++
+		case tok.text[0] == '?':
+			if i, err := strconv.Atoi(tok.text[1:]); err == nil {
+				n = max(n, i)
+			}
+		case !named[tok.text]:
+			named[tok.text] = true
+			n++
+		}
+	}
+	return n
+}
+
+// noRowsKeywords start statements that return no rows.
+var noRowsKeywords = []string{
+	"CREATE", "DROP", "ALTER", "BEGIN", "COMMIT", "END", "ROLLBACK", "SAVEPOINT",
+	"RELEASE", "ATTACH", "DETACH", "VACUUM", "REINDEX", "ANALYZE",
+}
+
+// countColumns returns the number of result columns of the first statement
+// in sql. It returns 0 for statements that return no rows, and ok=false if
+// the number cannot be determined, e.g. for "SELECT *" or PRAGMA statements.
+func countColumns(sql string) (n int, ok bool) {
+	tokens := statementTokens(sql)
+	if len(tokens) == 0 {
+		return 0, false
+	}
+	first := keyword(tokens[0])
+	if first == "WITH" {
+		// skip the common table expressions, they are in parentheses
+		for i, depth := 1, 0; i < len(tokens); i++ {
+			depth += parenDelta(tokens[i])
+			if kw := keyword(tokens[i]); depth == 0 && slices.Contains([]string{"SELECT", "VALUES", "INSERT", "REPLACE", "UPDATE", "DELETE"}, kw) {
+				first = kw
+				tokens = tokens[i:]
+				break
+			}
+		}
+	}
+	switch {
+	case first == "SELECT":
+		return countResultColumns(tokens[1:])
+	case first == "VALUES":
+		if len(tokens) < 2 || tokens[1].text != "(" {
+			return 0, false
+		}
+		n = 1
+		for i, depth := 2, 1; i < len(tokens) && depth > 0; i++ {
+			depth += parenDelta(tokens[i])
+			if depth == 1 && tokens[i].text == "," {
+				n++
+			}
+		}
+		return n, true
+	case slices.Contains([]string{"INSERT", "REPLACE", "UPDATE", "DELETE"}, first):
+		for i, depth := 1, 0; i < len(tokens); i++ {
+			depth += parenDelta(tokens[i])
+			if depth == 0 && keyword(tokens[i]) == "RETURNING" {
+				return countResultColumns(tokens[i+1:])
+			}
+		}
+		return 0, true
+	case slices.Contains(noRowsKeywords, first):
+		return 0, true
+	}
+	return 0, false
+}
+
+// selectEndKeywords end the result columns of a SELECT.
+var selectEndKeywords = []string{
+	"FROM", "WHERE", "GROUP", "HAVING", "WINDOW", "ORDER", "LIMIT", "UNION", "INTERSECT", "EXCEPT",
+}
+
+// countResultColumns counts the result columns in tokens, which start after
+// SELECT or RETURNING. It returns ok=false if a result column is * or
+// table.*.
+func countResultColumns(tokens []token) (n int, ok bool) {
+	if len(tokens) > 0 && (keyword(tokens[0]) == "DISTINCT" || keyword(tokens[0]) == "ALL") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return 0, false
+	}
+	n = 1
+	depth := 0
+	for i, tok := range tokens {
+		depth += parenDelta(tok)
+		if depth != 0 {
+			continue
+		}
+		if slices.Contains(selectEndKeywords, keyword(tok)) {
+			break
+		}
+		switch tok.text {
+		case ",":
+			n++
+		case "*":
+			if i == 0 || tokens[i-1].text == "," || tokens[i-1].text == "." {
+				return 0, false
+			}
+		}
+	}
+	return n, true
+}
+
+// parenDelta returns 1 for an opening and -1 for a closing parenthesis.
+func parenDelta(tok token) int {
+	switch {
+	case tok.kind != tokOther:
+		return 0
+	case tok.text == "(":
+		return 1
+	case tok.text == ")":
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestCountParams(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT ?, ?", 2},
+		{"SELECT ?5, ?", 6},
+		{"SELECT ?, ?1", 1},
+		{"SELECT :a, @b, $c, :a", 3},
+		{"SELECT :a, ?, :a, ?3", 3},
+		{"SELECT '?', \"?\", [?] -- ?\n/* ? */", 0},
+		{"SELECT ?; SELECT ?, ?", 1},
+	}
+	for _, tt := range tests {
+		isEq(t, tt.want, countParams(tt.sql))
+	}
+}
+
+func TestCountColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want int // -1 if unknown
+	}{
+		{"SELECT 1", 1},
+		{"select distinct a, b.c, count(*), max(x, y) AS m FROM t WHERE a = 1", 4},
+		{"SELECT a * b, 'x, y', \"c,d\" FROM t", 3},
+		{"SELECT CASE WHEN a THEN 1 ELSE 2 END, (SELECT x, y FROM u) FROM t", 2},
+		{"SELECT a FROM t UNION SELECT b FROM u", 1},
+		{"SELECT *", -1},
+		{"SELECT a, t.* FROM t", -1},
+		{"SELECT", -1},
+		{"VALUES (1, 2), (3, 4)", 2},
+		{"VALUES (max(1, 2))", 1},
+		{"WITH t(a, b) AS (SELECT 1, 2) SELECT a FROM t", 1},
+		{"WITH t AS (SELECT 1) INSERT INTO u SELECT * FROM t", 0},
+		{"INSERT INTO t (a, b) VALUES (1, 2)", 0},
+		{"INSERT INTO t (a, b) VALUES (1, 2) RETURNING a, b", 2},
+		{"UPDATE t SET a = 1 RETURNING *", -1},
+		{"DELETE FROM t WHERE a IN (SELECT b FROM u)", 0},
+		{"CREATE TABLE t (a, b)", 0},
+		{"PRAGMA table_info(t)", -1},
+		{"EXPLAIN SELECT 1", -1},
+		{"  -- comment\n SELECT 1, 2; SELECT 3", 2},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		n, ok := countColumns(tt.sql)
+		if !ok {
+			n = -1
+		}
+		isEq(t, tt.want, n)
+	}
+}
+
+func isEq[T comparable](t *testing.T, want, have T) {
+	t.Helper()
+	if want != have {
+		t.Fatalf("want %T(%v) but have %T(%v)", want, want, have, have)
+	}
+}
@@ -0,0 +1,34 @@
+package a
+
+import "github.com/cvilsmeier/sqinn-go/v2"
+
+const selectUsers = "SELECT id, name FROM users WHERE id > ?"
+
+func calls(sq *sqinn.Sqinn, sql string, n int, params []sqinn.Value, coltypes []byte) {
+	produce := func(params []sqinn.Value) {}
+	consume := func(row int, values []sqinn.Value) {}
+	id := sqinn.Int64Value(1)
+	// ok
+	sq.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 10, 2, produce)
+	sq.ExecParams("INSERT INTO users (id, name) VALUES (?, ?)", 1, 2, []sqinn.Value{id, sqinn.StringValue("a")})
+	sq.ExecParams("DELETE FROM users", 1, 0, nil)
+	sq.Query(selectUsers, []sqinn.Value{id}, []byte{sqinn.ValInt64, sqinn.ValString}, consume)
+	sq.QueryRows("SELECT * FROM users WHERE id = ?", []sqinn.Value{id}, []byte{sqinn.ValInt64})
+	sq.QueryRows("PRAGMA table_info(users)", nil, []byte{sqinn.ValString})
+	sq.MustQueryRows("SELECT :a, :b, :a", []sqinn.Value{id, id}, []byte{1, 1, 1})
+	sq.MustQueryRows("SELECT ?3, ?", []sqinn.Value{id, id, id, id}, []byte{1, 1})
+	// not constant
+	sq.Exec(sql, 1, 5, produce)
+	sq.ExecParams("DELETE FROM users WHERE id = ?", 1, n, params)
+	sq.QueryRows(selectUsers, params, coltypes)
+	// mismatches
+	sq.Exec("INSERT INTO users (id, name) VALUES (?, ?)", 10, 3, produce)                    // want `Exec: nparams is 3 but SQL has 2 parameters`
+	sq.MustExec("DELETE FROM users WHERE id = ?; SELECT ?", 1, 2, produce)                   // want `MustExec: nparams is 2 but SQL has 1 parameters`
+	sq.ExecParams("DELETE FROM users WHERE id = ?", 2, 1, []sqinn.Value{id})                 // want `ExecParams: have 1 params but niterations\*nparams is 2`
+	sq.MustExecParams("UPDATE users SET name = :name WHERE id = :id", 1, 3, []sqinn.Value{}) // want `MustExecParams: nparams is 3 but SQL has 2 parameters` `MustExecParams: have 0 params but niterations\*nparams is 3`
+	sq.Query(selectUsers, nil, []byte{sqinn.ValInt64, sqinn.ValString}, consume)             // want `Query: have 0 params but SQL has 1 parameters`
+	sq.Query(selectUsers, []sqinn.Value{id}, []byte{sqinn.ValInt64}, consume)                // want `Query: have 1 coltypes but SQL returns 2 columns`
+	sq.MustQuery("INSERT INTO users (name) VALUES ('a')", nil, []byte{1}, consume)           // want `MustQuery: have 1 coltypes but SQL returns no rows`
+	sq.QueryRows("DELETE FROM users RETURNING id, name", nil, []byte{1})                     // want `QueryRows: have 1 coltypes but SQL returns 2 columns`
+	sq.MustQueryRows("WITH t(x) AS (SELECT 1, 2) SELECT x FROM t", nil, []byte{1, 1})        // want `MustQueryRows: have 2 coltypes but SQL returns 1 columns`
+}
@@ -0,0 +1,37 @@
+// Package sqinn is a stub of the real package, for testing.
+package sqinn
+
+const (
+	ValNull   byte = 0
+	ValInt32  byte = 1
+	ValInt64  byte = 2
+	ValDouble byte = 3
+	ValString byte = 4
+	ValBlob   byte = 5
+)
+
+type Value struct{}
+
+func Int64Value(v int64) Value   { return Value{} }
+func StringValue(v string) Value { return Value{} }
+
+type ProduceFunc func(params []Value)
+type ConsumeFunc func(row int, values []Value)
+
+type Sqinn struct{}
+
+func (sq *Sqinn) Exec(sql string, niterations, nparams int, produce ProduceFunc) error  { return nil }
+func (sq *Sqinn) MustExec(sql string, niterations, nparams int, produce ProduceFunc)    {}
+func (sq *Sqinn) ExecParams(sql string, niterations, nparams int, params []Value) error { return nil }
+func (sq *Sqinn) MustExecParams(sql string, niterations, nparams int, params []Value)   {}
+func (sq *Sqinn) ExecSql(sql string) error                                              { return nil }
+func (sq *Sqinn) Query(sql string, params []Value, coltypes []byte, consume ConsumeFunc) error {
+	return nil
+}
+func (sq *Sqinn) MustQuery(sql string, params []Value, coltypes []byte, consume ConsumeFunc) {}
+func (sq *Sqinn) QueryRows(sql string, params []Value, coltypes []byte) ([][]Value, error) {
+	return nil, nil
+}
+func (sq *Sqinn) MustQueryRows(sql string, params []Value, coltypes []byte) [][]Value {
+	return nil
+}
@@ -0,0 +1,127 @@
+package sqinn
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codecs is a registry of codecs that convert custom Go types to sqinn
+// Values and back. Codecs are registered with RegisterCodec.
+// The zero Codecs is empty and ready to use.
+// A Codecs is safe for concurrent use.
+type Codecs struct {
+	mu sync.RWMutex
+	m  map[reflect.Type]codec
+}
+
+type codec struct {
+	encode func(v any) (Value, error)
+	decode func(v Value) (any, error)
+}
+
+// RegisterCodec registers a codec for Go type T. Encode converts a T to a
+// Value, decode converts a Value back to a T. Decode is also called for NULL
+// values. A codec that was registered before for T is replaced.
+func RegisterCodec[T any](c *Codecs, encode func(v T) (Value, error), decode func(v Value) (T, error)) {
+	typ := reflect.TypeFor[T]()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[reflect.Type]codec)
+	}
+	c.m[typ] = codec{
+		encode: func(v any) (Value, error) { return encode(v.(T)) },
+		decode: func(v Value) (any, error) { return decode(v) },
+	}
+}
+
+func (c *Codecs) lookup(typ reflect.Type) (codec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	co, ok := c.m[typ]
+	return co, ok
+}
+
+// Encode converts v to a Value. It returns ok=false if no codec
+// is registered for the type of v.
+func (c *Codecs) Encode(v any) (_ Value, ok bool, _ error) {
+	if v == nil {
+		return Value{}, false, nil
+	}
+	co, ok := c.lookup(reflect.TypeOf(v))
+	if !ok {
+		return Value{}, false, nil
+	}
+	value, err := co.encode(v)
+	return value, true, err
+}
+
+// Decode converts a Value into dst, which must be a non-nil pointer to a
+// Go type. It returns ok=false if no codec is registered for that Go type.
+func (c *Codecs) Decode(v Value, dst any) (ok bool, _ error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return false, fmt.Errorf("want non-nil pointer but have %T", dst)
+	}
+	co, ok := c.lookup(rv.Type().Elem())
+	if !ok {
+		return false, nil
+	}
+	x, err := co.decode(v)
+	if err != nil {
+		return true, err
+	}
+	if x == nil {
+		// a nil interface value
+		rv.Elem().Set(reflect.Zero(rv.Type().Elem()))
+		return true, nil
+	}
+	rv.Elem().Set(reflect.ValueOf(x))
+	return true, nil
+}
+
+// Bind is like the package-level Bind function but also uses the
+// codecs from Options.Codecs and the time format from Options.TimeFormat.
+func (sq *Sqinn) Bind(params []any) []Value {
+	return must(sq.BindE(params))
+}
+
+// BindE is like the package-level BindE function but also uses the
+// codecs from Options.Codecs and the time format from Options.TimeFormat.
+func (sq *Sqinn) BindE(params []any) ([]Value, error) {
+	return Binder{TimeFormat: sq.timefmt, Codecs: sq.codecs}.Bind(params)
+}
+
+// Scan is like the package-level Scan function but the returned Scanner
+// uses the codecs from Options.Codecs and the time format from
+// Options.TimeFormat.
+func (sq *Sqinn) Scan(values []Value) *Scanner {
+	return &Scanner{values, -1, sq.codecs, sq.timefmt, nil}
+}
+
+// ScanInto is like the package-level ScanInto function but also uses the
+// codecs from Options.Codecs and the time format from Options.TimeFormat.
+func (sq *Sqinn) ScanInto(values []Value, dests ...any) error {
+	if len(dests) != len(values) {
+		return fmt.Errorf("want %d dests but have %d", len(values), len(dests))
+	}
+	return sq.Scan(values).Scan(dests...)
+}
+
+// Decode decodes the next Value into dst, which must be a non-nil pointer
+// to a Go type for which a codec is registered.
+func (s *Scanner) Decode(dst any) error {
+	v := s.Next()
+	if s.codecs == nil {
+		return fmt.Errorf("decode: no codecs")
+	}
+	ok, err := s.codecs.Decode(v, dst)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("decode: no codec for %T", dst)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package sqinn
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+type testCents int64
+
+func testCodecs() *Codecs {
+	codecs := &Codecs{}
+	RegisterCodec(codecs, func(v testCents) (Value, error) {
+		return StringValue(fmt.Sprintf("%d.%02d", v/100, v%100)), nil
+	}, func(v Value) (testCents, error) {
+		var units, cents int64
+		if _, err := fmt.Sscanf(v.String, "%d.%d", &units, &cents); err != nil {
+			return 0, err
+		}
+		return testCents(units*100 + cents), nil
+	})
+	RegisterCodec(codecs, func(v netip.Addr) (Value, error) {
+		if !v.IsValid() {
+			return NullValue(), nil
+		}
+		return StringValue(v.String()), nil
+	}, func(v Value) (netip.Addr, error) {
+		if v.Type == ValNull {
+			return netip.Addr{}, nil
+		}
+		return netip.ParseAddr(v.String)
+	})
+	return codecs
+}
+
+func TestCodecs(t *testing.T) {
+	codecs := testCodecs()
+	// encode
+	v, ok, err := codecs.Encode(testCents(1234))
+	isNoErr(t, err)
+	isEq(t, true, ok)
+	isEq(t, "12.34", v.String)
+	_, ok, err = codecs.Encode(1234)
+	isNoErr(t, err)
+	isEq(t, false, ok)
+	_, ok, err = codecs.Encode(nil)
+	isNoErr(t, err)
+	isEq(t, false, ok)
+	// decode
+	var cents testCents
+	ok, err = codecs.Decode(StringValue("5.06"), &cents)
+	isNoErr(t, err)
+	isEq(t, true, ok)
+	isEq(t, testCents(506), cents)
+	var i int
+	ok, err = codecs.Decode(Int32Value(1), &i)
+	isNoErr(t, err)
+	isEq(t, false, ok)
+	// decode nil into an interface type
+	RegisterCodec(codecs, func(v fmt.Stringer) (Value, error) {
+		return StringValue(v.String()), nil
+	}, func(v Value) (fmt.Stringer, error) {
+		if v.Type == ValNull {
+			return nil, nil
+		}
+		return netip.ParseAddr(v.String)
+	})
+	var stringer fmt.Stringer = netip.MustParseAddr("127.0.0.1")
+	ok, err = codecs.Decode(NullValue(), &stringer)
+	isNoErr(t, err)
+	isEq(t, true, ok)
+	isEq(t, nil, stringer)
+	ok, err = codecs.Decode(StringValue("10.0.0.1"), &stringer)
+	isNoErr(t, err)
+	isEq(t, true, ok)
+	isEq(t, "10.0.0.1", stringer.String())
+	_, err = codecs.Decode(StringValue("5.06"), cents)
+	isErr(t, err, "want non-nil pointer but have sqinn.testCents")
+	_, err = codecs.Decode(StringValue("x"), &cents)
+	isErr(t, err, "expected integer")
+	// replace codec
+	RegisterCodec(codecs, func(v testCents) (Value, error) {
+		return Int64Value(int64(v)), nil
+	}, func(v Value) (testCents, error) {
+		return testCents(v.Int64), nil
+	})
+	v, _, _ = codecs.Encode(testCents(1234))
+	isEq(t, ValInt64, v.Type)
+	// binder uses codecs before custom hook
+	values, err := Binder{Codecs: codecs, Custom: func(v any) (Value, bool, error) {
+		return Value{}, false, errors.New("custom must not be called")
+	}}.Bind([]any{testCents(5)})
+	isNoErr(t, err)
+	isEq(t, 5, values[0].Int64)
+}
+
+func TestCodecsSqinn(t *testing.T) {
+	sq := MustLaunch(Options{Codecs: testCodecs()})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE hosts (addr TEXT, price TEXT)")
+	params, err := sq.BindE([]any{netip.MustParseAddr("192.168.0.1"), testCents(1999), netip.Addr{}, testCents(5)})
+	isNoErr(t, err)
+	sq.MustExecParams("INSERT INTO hosts (addr, price) VALUES (?, ?)", 2, 2, params)
+	rows := sq.MustQueryRows("SELECT addr, price FROM hosts ORDER BY rowid", nil, []byte{ValString, ValString})
+	isEq(t, 2, len(rows))
+	var addr netip.Addr
+	var price testCents
+	sc := sq.Scan(rows[0])
+	isNoErr(t, sc.Decode(&addr))
+	isNoErr(t, sc.Decode(&price))
+	isEq(t, netip.MustParseAddr("192.168.0.1"), addr)
+	isEq(t, testCents(1999), price)
+	sc = sq.Scan(rows[1])
+	isNoErr(t, sc.Decode(&addr))
+	isNoErr(t, sc.Decode(&price))
+	isEq(t, false, addr.IsValid())
+	isEq(t, testCents(5), price)
+	// errors
+	sc = sq.Scan(rows[0])
+	var s string
+	isErr(t, sc.Decode(&s), "decode: no codec for *string")
+	err = sc.Decode(&addr)
+	isTrue(t, err != nil && strings.HasPrefix(err.Error(), `decode: ParseAddr("19.99")`), "wrong err %v", err)
+	sc = Scan(rows[0])
+	isErr(t, sc.Decode(&addr), "decode: no codecs")
+	_, err = sq.BindE([]any{struct{}{}})
+	isErr(t, err, "sqinn.Bind(): param 0: wrong Go type struct {}")
+}
@@ -0,0 +1,381 @@
+package sqinn
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVOptions control how ImportCSV reads and imports a CSV file.
+type CSVOptions struct {
+	// Comma is the field delimiter.
+	// Default is ','.
+	Comma rune
+
+	// Header tells ImportCSV that the first record holds the column names.
+	// Default is false.
+	Header bool
+
+	// Columns are the names of the table columns, one for each field.
+	// If Columns is not empty, it is used instead of the header record.
+	// If Columns is empty and Header is false, the columns of the existing
+	// table are used, or, if CreateTable is true, "c1", "c2", etc.
+	// Default is empty.
+	Columns []string
+
+	// Null is the NULL marker. Fields that are equal to Null are imported
+	// as NULL. If Null is empty, empty fields are imported as NULL.
+	// Default is empty.
+	Null string
+
+	// CreateTable tells ImportCSV to create the table if it does not exist.
+	// The column types (INTEGER, REAL or TEXT) are inferred from the first
+	// SampleRows records.
+	// Default is false.
+	CreateTable bool
+
+	// SampleRows is the number of records used for type inference.
+	// Default is 100.
+	SampleRows int
+
+	// BatchSize is the number of records that are inserted by one batch.
+	// Default is 1000.
+	BatchSize int
+
+	// MaxRejects is the maximum number of rejected lines. If more lines are
+	// rejected, ImportCSV stops. A negative value allows unlimited rejects.
+	// Default is 0 (stop at the first rejected line).
+	MaxRejects int
+}
+
+// CSVResult is the result of ImportCSV.
+type CSVResult struct {
+	Imported int          // The number of imported records.
+	Rejects  []*CSVReject // The rejected records.
+}
+
+// A CSVReject describes a CSV record that could not be imported.
+type CSVReject struct {
+	Line int   // The line number of the record, starting at 1.
+	Err  error // Why the record was rejected.
+}
+
+func (e *CSVReject) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *CSVReject) Unwrap() error {
+	return e.Err
+}
+
+// ErrTooManyRejects is returned by ImportCSV if more than
+// CSVOptions.MaxRejects lines were rejected.
+var ErrTooManyRejects = errors.New("too many rejected lines")
+
+// ImportCSV reads CSV records from r and inserts them into table.
+//
+// The records are streamed: ImportCSV holds at most opt.BatchSize records in
+// memory, and inserts them with ExecBatch, each batch inside a SAVEPOINT.
+// Fields are bound as ValString or NULL, SQLite converts them according to
+// the column affinity, see https://www.sqlite.org/datatype3.html.
+//
+// Records that cannot be parsed, have the wrong number of fields, or violate
+// a constraint are rejected and reported with their line number in the result.
+// If more than opt.MaxRejects records are rejected, ImportCSV stops and returns
+// ErrTooManyRejects. If ctx is done, ImportCSV stops and returns ctx.Err().
+// In both cases, batches that were inserted before are not rolled back. Wrap
+// ImportCSV in a transaction if an all-or-nothing import is needed.
+func (sq *Sqinn) ImportCSV(ctx context.Context, r io.Reader, table string, opt CSVOptions) (CSVResult, error) {
+	var result CSVResult
+	if opt.SampleRows <= 0 {
+		opt.SampleRows = 100
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 1000
+	}
+	cr := csv.NewReader(r)
+	if opt.Comma != 0 {
+		cr.Comma = opt.Comma
+	}
+	cr.FieldsPerRecord = -1 // we check the field count ourselves
+	imp := &csvImport{sq: sq, cr: cr, opt: opt, result: &result}
+	columns := opt.Columns
+	if opt.Header {
+		header, _, err := imp.read()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("no header")
+			}
+			return result, err
+		}
+		if len(columns) == 0 {
+			columns = header
+		}
+	}
+	// read sample records for type inference and column count
+	var sample []csvRecord
+	var readErr error
+	if opt.CreateTable {
+		for len(sample) < opt.SampleRows {
+			rec, ok, err := imp.next()
+			if err != nil {
+				readErr = err
+				break
+			}
+			if !ok {
+				break
+			}
+			sample = append(sample, rec)
+		}
+	}
+	if readErr != nil {
+		return result, readErr
+	}
+	columns, err := sq.importTable(table, columns, sample, opt)
+	if err != nil {
+		return result, err
+	}
+	imp.ncols = len(columns)
+	sql := insertSql(table, columns, 1, InsertOptions{})
+	batch := make([]csvRecord, 0, opt.BatchSize)
+	for _, rec := range sample {
+		if imp.check(rec) {
+			batch = append(batch, rec)
+		}
+	}
+	if err := imp.tooManyRejects(); err != nil {
+		return result, err
+	}
+	for readErr == nil {
+		if len(batch) >= opt.BatchSize {
+			if err := imp.insert(ctx, sql, batch); err != nil {
+				return result, err
+			}
+			batch = batch[:0]
+		}
+		rec, ok, err := imp.next()
+		if err != nil {
+			readErr = err
+			break
+		}
+		if !ok {
+			break
+		}
+		if imp.check(rec) {
+			batch = append(batch, rec)
+		}
+		if err := imp.tooManyRejects(); err != nil {
+			return result, err
+		}
+	}
+	if readErr != nil {
+		return result, readErr
+	}
+	if err := imp.insert(ctx, sql, batch); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// MustImportCSV is the same as ImportCSV except it panics on error.
+func (sq *Sqinn) MustImportCSV(ctx context.Context, r io.Reader, table string, opt CSVOptions) CSVResult {
+	return must(sq.ImportCSV(ctx, r, table, opt))
+}
+
+// importTable checks that table exists, or creates it if opt.CreateTable
+// is set, and returns the columns to import into.
+func (sq *Sqinn) importTable(table string, columns []string, sample []csvRecord, opt CSVOptions) ([]string, error) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	exists, err := sq.tableExists(table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists && !opt.CreateTable {
+		return nil, fmt.Errorf("table %q not found", table)
+	}
+	if !exists {
+		if len(columns) == 0 {
+			if len(sample) == 0 {
+				return nil, errors.New("cannot infer columns: no records")
+			}
+			for i := range sample[0].fields {
+				columns = append(columns, fmt.Sprintf("c%d", i+1))
+			}
+		}
+		if err := sq.execSql(createTableSql(table, columns, sample, opt.Null)); err != nil {
+			return nil, err
+		}
+	}
+	if len(columns) == 0 {
+		return sq.tableColumns(table)
+	}
+	return columns, nil
+}
+
+type csvRecord struct {
+	line   int
+	fields []string
+}
+
+type csvImport struct {
+	sq     *Sqinn
+	cr     *csv.Reader
+	opt    CSVOptions
+	ncols  int
+	result *CSVResult
+}
+
+// read reads the next record. It returns io.EOF at the end of input,
+// and a *csv.ParseError for malformed records.
+func (imp *csvImport) read() ([]string, int, error) {
+	fields, err := imp.cr.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := imp.cr.FieldPos(0)
+	return fields, line, nil
+}
+
+// next reads the next well-formed record and rejects malformed records.
+// It returns ok=false at the end of input, and an error if reading fails.
+func (imp *csvImport) next() (csvRecord, bool, error) {
+	for {
+		fields, line, err := imp.read()
+		if err == io.EOF {
+			return csvRecord{}, false, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			imp.reject(parseErr.StartLine, parseErr.Err)
+			if err := imp.tooManyRejects(); err != nil {
+				return csvRecord{}, false, err
+			}
+			continue
+		}
+		if err != nil {
+			return csvRecord{}, false, err
+		}
+		return csvRecord{line, fields}, true, nil
+	}
+}
+
+// check rejects a record with a wrong number of fields.
+func (imp *csvImport) check(rec csvRecord) bool {
+	if len(rec.fields) != imp.ncols {
+		imp.reject(rec.line, fmt.Errorf("want %d fields but have %d", imp.ncols, len(rec.fields)))
+		return false
+	}
+	return true
+}
+
+func (imp *csvImport) reject(line int, err error) {
+	imp.result.Rejects = append(imp.result.Rejects, &CSVReject{line, err})
+}
+
+func (imp *csvImport) tooManyRejects() error {
+	if imp.opt.MaxRejects >= 0 && len(imp.result.Rejects) > imp.opt.MaxRejects {
+		return ErrTooManyRejects
+	}
+	return nil
+}
+
+// insert inserts a batch of records and rejects failing records.
+func (imp *csvImport) insert(ctx context.Context, sql string, batch []csvRecord) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	failures, err := imp.sq.ExecBatch(sql, len(batch), imp.ncols, func(iteration int, params []Value) {
+		for i, field := range batch[iteration].fields {
+			if field == imp.opt.Null {
+				params[i] = NullValue()
+			} else {
+				params[i] = StringValue(field)
+			}
+		}
+	}, BatchOptions{ContinueOnError: true, ChunkSize: len(batch)})
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		imp.reject(batch[failure.Iteration].line, failure.Err)
+	}
+	imp.result.Imported += len(batch) - len(failures)
+	return imp.tooManyRejects()
+}
+
+// tableExists reports whether a table exists in the main schema.
+// The caller must hold sq.mu.
+func (sq *Sqinn) tableExists(table string) (bool, error) {
+	var count int
+	err := sq.query("tableExists", "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE", []Value{StringValue(table)}, []byte{ValInt32}, func(row int, values []Value) {
+		count = values[0].Int32
+	})
+	return count > 0, err
+}
+
+// tableColumns returns the column names of a table, in declaration order.
+// The caller must hold sq.mu.
+func (sq *Sqinn) tableColumns(table string) ([]string, error) {
+	var columns []string
+	err := sq.query("tableColumns", "SELECT name FROM pragma_table_info(?) ORDER BY cid", []Value{StringValue(table)}, []byte{ValString}, func(row int, values []Value) {
+		columns = append(columns, values[0].String)
+	})
+	return columns, err
+}
+
+// createTableSql builds a CREATE TABLE statement with column types
+// inferred from sample records.
+func createTableSql(table string, columns []string, sample []csvRecord, null string) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(quoteIdent(table))
+	sb.WriteString(" (")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoteIdent(col))
+		sb.WriteString(" ")
+		sb.WriteString(inferColumnType(sample, i, null))
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// inferColumnType returns INTEGER if all non-NULL fields of a column are
+// integers, REAL if they are numbers, and TEXT otherwise. Numbers with
+// leading zeros, like zip codes, are TEXT.
+func inferColumnType(sample []csvRecord, col int, null string) string {
+	typ := "INTEGER"
+	nvalues := 0
+	for _, rec := range sample {
+		if col >= len(rec.fields) || rec.fields[col] == null {
+			continue
+		}
+		field := rec.fields[col]
+		nvalues++
+		if digits := strings.TrimLeft(field, "+-"); len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+			return "TEXT"
+		}
+		if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+			continue
+		}
+		if _, err := strconv.ParseFloat(field, 64); err == nil && !strings.ContainsAny(field, "xXnN_") {
+			typ = "REAL"
+			continue
+		}
+		return "TEXT"
+	}
+	if nvalues == 0 {
+		return "TEXT"
+	}
+	return typ
+}
@@ -0,0 +1,167 @@
+package sqinn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	ctx := context.Background()
+	// create table with inferred types
+	input := "id;name;score;zip\n" +
+		"1;Alice;1.5;01234\n" +
+		"2;\"Bob; Jr.\";2;\n" +
+		"3;\\N;-1e3;99999\n"
+	result, err := sq.ImportCSV(ctx, strings.NewReader(input), "users", CSVOptions{Comma: ';', Header: true, Null: `\N`, CreateTable: true})
+	isNoErr(t, err)
+	isEq(t, 3, result.Imported)
+	isEq(t, 0, len(result.Rejects))
+	rows := sq.MustQueryRows("SELECT name, type FROM pragma_table_info('users') ORDER BY cid", nil, []byte{ValString, ValString})
+	isEq(t, "id INTEGER, name TEXT, score REAL, zip TEXT", fmt.Sprintf("%s %s, %s %s, %s %s, %s %s",
+		rows[0][0].String, rows[0][1].String, rows[1][0].String, rows[1][1].String,
+		rows[2][0].String, rows[2][1].String, rows[3][0].String, rows[3][1].String))
+	rows = sq.MustQueryRows("SELECT id, quote(name), typeof(score), score, quote(zip) FROM users ORDER BY id", nil, []byte{ValInt32, ValString, ValString, ValDouble, ValString})
+	isEq(t, 3, len(rows))
+	isEq(t, "'Alice'", rows[0][1].String)
+	isEq(t, "real", rows[0][2].String)
+	isEq(t, "'01234'", rows[0][4].String)
+	isEq(t, "'Bob; Jr.'", rows[1][1].String)
+	isEq(t, 2.0, rows[1][3].Double)
+	isEq(t, "''", rows[1][4].String)
+	isEq(t, "NULL", rows[2][1].String)
+	isEq(t, -1000.0, rows[2][3].Double)
+	// table names are case-insensitive, the table exists already
+	result, err = sq.ImportCSV(ctx, strings.NewReader("id;name;score;zip\n4;Dan;1;1\n"), "Users", CSVOptions{Comma: ';', Header: true, CreateTable: true})
+	isNoErr(t, err)
+	isEq(t, 1, result.Imported)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM users", nil, []byte{ValInt32})
+	isEq(t, 4, rows[0][0].Int32)
+	sq.MustExecSql("DELETE FROM users WHERE id = 4")
+	// import into an existing table with rejects
+	sq.MustExecSql("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	input = "1,one\n" +
+		"2,\n" + // NOT NULL violation
+		"3,three,extra\n" + // wrong field count
+		"4,\"fo\"ur\"\n" + // parse error
+		"5,five\n" +
+		"1,dup\n" + // PRIMARY KEY violation
+		"6,six\n"
+	result, err = sq.ImportCSV(ctx, strings.NewReader(input), "items", CSVOptions{MaxRejects: -1, BatchSize: 2})
+	isNoErr(t, err)
+	isEq(t, 3, result.Imported)
+	isEq(t, 4, len(result.Rejects))
+	isEq(t, "line 2: sqinn: NOT NULL constraint failed: items.name", result.Rejects[0].Error())
+	isEq(t, "line 3: want 2 fields but have 3", result.Rejects[1].Error())
+	isEq(t, "line 4: extraneous or missing \" in quoted-field", result.Rejects[2].Error())
+	isEq(t, "line 6: sqinn: UNIQUE constraint failed: items.id", result.Rejects[3].Error())
+	rows = sq.MustQueryRows("SELECT id, name FROM items ORDER BY id", nil, []byte{ValInt32, ValString})
+	isEq(t, 3, len(rows))
+	isEq(t, "six", rows[2][1].String)
+	// max rejects
+	sq.MustExecSql("DELETE FROM items")
+	result, err = sq.ImportCSV(ctx, strings.NewReader(input), "items", CSVOptions{MaxRejects: 1, BatchSize: 1})
+	isTrue(t, errors.Is(err, ErrTooManyRejects), "want ErrTooManyRejects but have %v", err)
+	isEq(t, 1, result.Imported)
+	isEq(t, 2, len(result.Rejects))
+	// explicit columns, no table creation
+	sq.MustExecSql("CREATE TABLE pairs (a TEXT, b INTEGER, c TEXT)")
+	result, err = sq.ImportCSV(ctx, strings.NewReader("x\t1\ny\t2\n"), "pairs", CSVOptions{Comma: '\t', Columns: []string{"c", "b"}})
+	isNoErr(t, err)
+	isEq(t, 2, result.Imported)
+	rows = sq.MustQueryRows("SELECT quote(a), typeof(b), c FROM pairs ORDER BY b", nil, []byte{ValString, ValString, ValString})
+	isEq(t, "NULL", rows[0][0].String)
+	isEq(t, "integer", rows[0][1].String)
+	isEq(t, "x", rows[0][2].String)
+	// generated column names
+	result, err = sq.ImportCSV(ctx, strings.NewReader("1,a\n2,b\n"), "generated", CSVOptions{CreateTable: true})
+	isNoErr(t, err)
+	isEq(t, 2, result.Imported)
+	rows = sq.MustQueryRows("SELECT name FROM pragma_table_info('generated') ORDER BY cid", nil, []byte{ValString})
+	isEq(t, "c1", rows[0][0].String)
+	isEq(t, "c2", rows[1][0].String)
+	// errors
+	_, err = sq.ImportCSV(ctx, strings.NewReader("1,a\n"), "no_such_table", CSVOptions{})
+	isErr(t, err, `table "no_such_table" not found`)
+	_, err = sq.ImportCSV(ctx, strings.NewReader(""), "items", CSVOptions{Header: true})
+	isErr(t, err, "no header")
+	_, err = sq.ImportCSV(ctx, strings.NewReader(""), "empty", CSVOptions{CreateTable: true})
+	isErr(t, err, "cannot infer columns: no records")
+	// too many rejects in the sample, the table is not created
+	_, err = sq.ImportCSV(ctx, strings.NewReader("a,b\n1,\"x\"y\"\n"), "rejected", CSVOptions{Header: true, CreateTable: true})
+	isTrue(t, errors.Is(err, ErrTooManyRejects), "want ErrTooManyRejects but have %v", err)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM sqlite_master WHERE name = 'rejected'", nil, []byte{ValInt32})
+	isEq(t, 0, rows[0][0].Int32)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sq.ImportCSV(canceled, strings.NewReader("1,a\n"), "generated", CSVOptions{})
+	isTrue(t, errors.Is(err, context.Canceled), "want context.Canceled but have %v", err)
+}
+
+func TestImportCSVConcurrent(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	sq.MustExecSql("CREATE TABLE logs (id INTEGER PRIMARY KEY NOT NULL)")
+	// every tenth record is rejected, the batch is rolled back and replayed
+	var sb strings.Builder
+	for i := range 2000 {
+		if i%10 == 0 {
+			fmt.Fprintf(&sb, "%d,\\N\n", i)
+		} else {
+			fmt.Fprintf(&sb, "%d,item\n", i)
+		}
+	}
+	// another goroutine writes while ImportCSV rolls back
+	stop := make(chan struct{})
+	done := make(chan error)
+	nlogs := 0
+	go func() {
+		for {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if err := sq.ExecParams("INSERT INTO logs (id) VALUES(?)", 1, 1, []Value{Int32Value(nlogs)}); err != nil {
+				done <- err
+				return
+			}
+			nlogs++
+		}
+	}()
+	result, err := sq.ImportCSV(context.Background(), strings.NewReader(sb.String()), "items", CSVOptions{Null: `\N`, MaxRejects: -1, BatchSize: 5})
+	close(stop)
+	isNoErr(t, <-done)
+	isNoErr(t, err)
+	isEq(t, 1800, result.Imported)
+	isEq(t, 200, len(result.Rejects))
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM logs", nil, []byte{ValInt32})
+	isEq(t, nlogs, rows[0][0].Int32)
+}
+
+func TestInferColumnType(t *testing.T) {
+	sample := func(fields ...string) []csvRecord {
+		var recs []csvRecord
+		for i, f := range fields {
+			recs = append(recs, csvRecord{i + 1, []string{f}})
+		}
+		return recs
+	}
+	isEq(t, "INTEGER", inferColumnType(sample("1", "-2", "", "0"), 0, ""))
+	isEq(t, "REAL", inferColumnType(sample("1", "2.5", "0.5"), 0, ""))
+	isEq(t, "TEXT", inferColumnType(sample("1", "abc"), 0, ""))
+	isEq(t, "TEXT", inferColumnType(sample("007"), 0, ""))
+	isEq(t, "TEXT", inferColumnType(sample("NaN", "Inf", "0x10"), 0, ""))
+	isEq(t, "TEXT", inferColumnType(sample("", ""), 0, ""))
+	isEq(t, "INTEGER", inferColumnType(sample("NULL", "1"), 0, "NULL"))
+}
@@ -0,0 +1,99 @@
+package sqinn
+
+import "strings"
+
+// A Description describes the parameters and result columns of a
+// SQL statement, see Describe.
+type Description struct {
+	// NumParams is the number of parameters. For numbered parameters like
+	// ?NNN, it is the largest parameter number.
+	NumParams int
+
+	// Columns are the result columns, or nil if the statement returns no rows.
+	Columns []ColumnDescription
+}
+
+// A ColumnDescription describes a result column of a SQL statement.
+type ColumnDescription struct {
+	// Name is the column name, or empty if it is not known.
+	Name string
+
+	// Decl is the declared type of the table column that the result column
+	// refers to, e.g. "INTEGER" or "VARCHAR(20)". It is empty for result
+	// columns that are expressions.
+	Decl string
+}
+
+// Describe determines the parameters and result columns of a SQL statement,
+// without executing it. Only the first statement of sql is described.
+//
+// The parameter count and the number of result columns are taken from the
+// EXPLAIN output. Column names and declared types are taken from a
+// temporary view. Statements that cannot be used in a view, e.g. INSERT
+// with a RETURNING clause, have result columns with empty names and
+// declared types.
+func (sq *Sqinn) Describe(sql string) (*Description, error) {
+	desc := &Description{}
+	ncols := -1
+	// EXPLAIN columns: addr, opcode, p1, p2, p3, p4, p5, comment
+	// The statement is not executed, its parameters need no values, so
+	// the parameter count check of Query is bypassed.
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	err := sq.query("Describe", "EXPLAIN "+sql, nil, []byte{ValInt32, ValString, ValInt32, ValInt32}, func(row int, values []Value) {
+		switch values[1].String {
+		case "Variable":
+			desc.NumParams = max(desc.NumParams, values[2].Int32)
+		case "ResultRow":
+			ncols = values[3].Int32
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ncols < 0 {
+		return desc, nil
+	}
+	desc.Columns = make([]ColumnDescription, ncols)
+	// The temporary view lives in a savepoint, which is rolled back. Holding
+	// sq.mu keeps other goroutines from writing inside the savepoint.
+	if err := sq.execSql("SAVEPOINT sqinn_describe"); err != nil {
+		return nil, err
+	}
+	// views must not have parameters, replace them with NULL
+	if err := sq.execSql("CREATE TEMP VIEW sqinn_describe AS " + replaceParams(sql, "NULL")); err != nil {
+		// not a SELECT statement
+		if err := sq.rollbackTo("sqinn_describe", nil); err != nil {
+			return nil, err
+		}
+		return desc, nil
+	}
+	err = sq.query("Describe", "SELECT cid, name, type FROM pragma_table_info('sqinn_describe')", nil, []byte{ValInt32, ValString, ValString}, func(row int, values []Value) {
+		if cid := values[0].Int32; cid < ncols {
+			desc.Columns[cid] = ColumnDescription{values[1].String, values[2].String}
+		}
+	})
+	if err := sq.rollbackTo("sqinn_describe", err); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// replaceParams replaces the parameters of the first statement of sql with
+// repl, and drops all further statements.
+func replaceParams(sql string, repl string) string {
+	var sb strings.Builder
+	lex := lexer{sql: sql}
+	for {
+		tok, ok := lex.next()
+		if !ok || tok.kind == tokSemicolon {
+			break
+		}
+		if tok.kind == tokParam {
+			sb.WriteString(repl)
+		} else {
+			sb.WriteString(tok.text)
+		}
+	}
+	return sb.String()
+}
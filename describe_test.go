@@ -0,0 +1,85 @@
+package sqinn
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(20) NOT NULL, score REAL)")
+	desc, err := sq.Describe("SELECT id, name, score * 2 AS double_score FROM users WHERE id > ? AND name <> ? LIMIT ?")
+	isNoErr(t, err)
+	isEq(t, 3, desc.NumParams)
+	isEq(t, 3, len(desc.Columns))
+	isEq(t, ColumnDescription{"id", "INTEGER"}, desc.Columns[0])
+	isEq(t, ColumnDescription{"name", "VARCHAR(20)"}, desc.Columns[1])
+	isEq(t, ColumnDescription{"double_score", ""}, desc.Columns[2])
+	// numbered and named params
+	desc, err = sq.Describe("SELECT :a, ?5, :a, ?; SELECT 1")
+	isNoErr(t, err)
+	isEq(t, 6, desc.NumParams)
+	isEq(t, 4, len(desc.Columns))
+	// no result
+	desc, err = sq.Describe("INSERT INTO users (id, name) VALUES (?, ?)")
+	isNoErr(t, err)
+	isEq(t, 2, desc.NumParams)
+	isTrue(t, desc.Columns == nil, "want nil columns but have %v", desc.Columns)
+	// RETURNING cannot be used in a view
+	desc, err = sq.Describe("DELETE FROM users WHERE id = ? RETURNING id, name")
+	isNoErr(t, err)
+	isEq(t, 1, desc.NumParams)
+	isEq(t, 2, len(desc.Columns))
+	isEq(t, ColumnDescription{}, desc.Columns[0])
+	// Describe leaves no trace
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM sqlite_temp_master", nil, []byte{ValInt32})
+	isEq(t, 0, rows[0][0].Int32)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM users", nil, []byte{ValInt32})
+	isEq(t, 0, rows[0][0].Int32)
+	// errors
+	_, err = sq.Describe("SELECT nope FROM users")
+	isErr(t, err, "sqinn: no such column: nope")
+}
+
+func TestDescribeConcurrent(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE logs (msg TEXT)")
+	// another goroutine writes while Describe creates and rolls back its view
+	stop := make(chan struct{})
+	done := make(chan error)
+	nlogs := 0
+	go func() {
+		for {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if err := sq.ExecSql("INSERT INTO logs VALUES ('x')"); err != nil {
+				done <- err
+				return
+			}
+			nlogs++
+		}
+	}()
+	for range 200 {
+		desc, err := sq.Describe("SELECT msg FROM logs WHERE msg = ?")
+		isNoErr(t, err)
+		isTrue(t, slices.Equal([]ColumnDescription{{"msg", "TEXT"}}, desc.Columns), "have %v", desc.Columns)
+	}
+	close(stop)
+	isNoErr(t, <-done)
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM logs", nil, []byte{ValInt32})
+	isEq(t, nlogs, rows[0][0].Int32)
+}
+
+func TestReplaceParams(t *testing.T) {
+	isEq(t, "SELECT NULL, ':a', NULL FROM t WHERE x = NULL ", replaceParams("SELECT ?, ':a', :a FROM t WHERE x = ?12 ; SELECT ?", "NULL"))
+}
@@ -0,0 +1,165 @@
+package sqinn
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DumpOptions control what Dump writes.
+type DumpOptions struct {
+	// Tables are the names of the tables and views to dump. Indexes and
+	// triggers are dumped if the table they belong to is dumped.
+	// Default is empty (dump all tables and views).
+	Tables []string
+
+	// SchemaOnly omits the INSERT statements for table rows.
+	// Default is false.
+	SchemaOnly bool
+}
+
+// Dump writes a SQL script to w that recreates the schema and data of the
+// main database, like the ".dump" command of the sqlite3 shell. The script
+// can be executed with LoadScript.
+//
+// The script contains, in this order: CREATE TABLE statements, each followed
+// by INSERT statements for its rows, the content of sqlite_sequence for
+// AUTOINCREMENT tables, and CREATE INDEX, CREATE TRIGGER and CREATE VIEW
+// statements in the order in which they were created, so that e.g. an
+// INSTEAD OF trigger follows its view. Values are written as SQL literals by
+// SQLite's quote() function, blobs as X'..' literals. The script runs in one
+// transaction.
+//
+// Dump reads the database inside a SAVEPOINT and holds the sqinn instance
+// until it is done, so the script reflects a consistent snapshot. Rows are
+// streamed to w, one row at a time.
+func (sq *Sqinn) Dump(w io.Writer, opt DumpOptions) error {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if err := sq.execSql("SAVEPOINT sqinn_dump"); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	err := sq.dump(bw, opt)
+	if releaseErr := sq.execSql("RELEASE sqinn_dump"); err == nil {
+		err = releaseErr
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// MustDump is the same as Dump except it panics on error.
+func (sq *Sqinn) MustDump(w io.Writer, opt DumpOptions) {
+	must(0, sq.Dump(w, opt))
+}
+
+type schemaEntry struct {
+	typ     string // "table", "index", "trigger" or "view"
+	name    string
+	tblName string
+	sql     string
+}
+
+// dump writes the script. The caller must hold sq.mu.
+func (sq *Sqinn) dump(w *bufio.Writer, opt DumpOptions) error {
+	var entries []schemaEntry
+	err := sq.query("Dump", "SELECT type, name, tbl_name, sql FROM sqlite_master"+
+		" WHERE sql NOT NULL AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\'"+
+		" AND name NOT IN (SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'shadow')"+
+		" ORDER BY rowid", nil,
+		[]byte{ValString, ValString, ValString, ValString},
+		func(row int, values []Value) {
+			entries = append(entries, schemaEntry{values[0].String, values[1].String, values[2].String, values[3].String})
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(opt.Tables) > 0 {
+		entries = slices.DeleteFunc(entries, func(e schemaEntry) bool {
+			return !slices.Contains(opt.Tables, e.tblName)
+		})
+	}
+	var writeErr error
+	write := func(s string) {
+		if writeErr == nil {
+			_, writeErr = w.WriteString(s)
+		}
+	}
+	write("PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n")
+	var dataTables []string
+	for _, e := range entries {
+		if e.typ != "table" {
+			continue
+		}
+		write(e.sql + ";\n")
+		if opt.SchemaOnly || strings.HasPrefix(strings.ToUpper(e.sql), "CREATE VIRTUAL TABLE") {
+			continue
+		}
+		if err := sq.dumpRows(e.name, write); err != nil {
+			return err
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+		dataTables = append(dataTables, e.name)
+	}
+	if !opt.SchemaOnly && len(dataTables) > 0 {
+		if err := sq.dumpSequence(dataTables, write); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		if e.typ != "table" {
+			write(e.sql + ";\n")
+		}
+	}
+	write("COMMIT;\n")
+	return writeErr
+}
+
+// dumpRows writes INSERT statements for all rows of a table.
+// The caller must hold sq.mu.
+func (sq *Sqinn) dumpRows(table string, write func(string)) error {
+	columns, err := sq.tableColumns(table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdent(col)
+	}
+	insert := "INSERT INTO " + quoteIdent(table) + "(" + strings.Join(quoted, ",") + ") VALUES("
+	for i, col := range quoted {
+		quoted[i] = "quote(" + col + ")"
+	}
+	sql := "SELECT " + strings.Join(quoted, " || ',' || ") + " FROM " + quoteIdent(table)
+	return sq.query("Dump", sql, nil, []byte{ValString}, func(row int, values []Value) {
+		write(insert + values[0].String + ");\n")
+	})
+}
+
+// dumpSequence writes the sqlite_sequence rows of the dumped tables, if
+// the database has AUTOINCREMENT tables.
+// The caller must hold sq.mu.
+func (sq *Sqinn) dumpSequence(tables []string, write func(string)) error {
+	exists, err := sq.tableExists("sqlite_sequence")
+	if err != nil || !exists {
+		return err
+	}
+	// CREATE TABLE with AUTOINCREMENT has created sqlite_sequence already,
+	// and the INSERTs of the table rows have filled it.
+	return sq.query("Dump", "SELECT name, quote(name), quote(seq) FROM sqlite_sequence ORDER BY rowid", nil, []byte{ValString, ValString, ValString}, func(row int, values []Value) {
+		if slices.Contains(tables, values[0].String) {
+			name, seq := values[1].String, values[2].String
+			write("DELETE FROM sqlite_sequence WHERE name = " + name + ";\n")
+			write("INSERT INTO sqlite_sequence(name,seq) VALUES(" + name + "," + seq + ");\n")
+		}
+	})
+}
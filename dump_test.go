@@ -0,0 +1,137 @@
+package sqinn
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testDumpSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, score REAL, avatar BLOB);
+CREATE TABLE "odd ""name""" (a TEXT, b INTEGER GENERATED ALWAYS AS (length(a)) VIRTUAL);
+CREATE TABLE log (msg TEXT);
+CREATE INDEX users_name ON users (name);
+CREATE TRIGGER users_log AFTER INSERT ON users BEGIN
+	INSERT INTO log (msg) VALUES ('inserted; ' || new.name);
+END;
+CREATE VIEW user_names AS SELECT name FROM users;
+CREATE TRIGGER user_names_insert INSTEAD OF INSERT ON user_names BEGIN
+	INSERT INTO users (name) VALUES (new.name);
+END;
+CREATE INDEX users_score ON users (score);
+`
+
+func testDumpSqinn(t *testing.T) *Sqinn {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	return sq
+}
+
+func TestDump(t *testing.T) {
+	sq := testDumpSqinn(t)
+	sq.MustLoadScript(strings.NewReader(testDumpSchema))
+	score := 0.1
+	score += 0.2 // 0.30000000000000004
+	sq.MustExecParams("INSERT INTO users (name, score, avatar) VALUES (?, ?, ?)", 3, 3, []Value{
+		StringValue("Alice"), DoubleValue(score), BlobValue([]byte{0, 0xFF}),
+		StringValue("O'Brien\nline 2"), NullValue(), NullValue(),
+		StringValue("Carol"), DoubleValue(1e300), BlobValue([]byte{1}),
+	})
+	sq.MustExecSql("DELETE FROM users WHERE name = 'Carol'")
+	sq.MustExecSql(`INSERT INTO "odd ""name""" (a) VALUES ('x;y')`)
+	var buf bytes.Buffer
+	isNoErr(t, sq.Dump(&buf, DumpOptions{}))
+	dump := buf.String()
+	want := `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, score REAL, avatar BLOB);
+INSERT INTO "users"("id","name","score","avatar") VALUES(1,'Alice',0.30000000000000004,X'00FF');
+INSERT INTO "users"("id","name","score","avatar") VALUES(2,'O''Brien
+line 2',NULL,NULL);
+CREATE TABLE "odd ""name""" (a TEXT, b INTEGER GENERATED ALWAYS AS (length(a)) VIRTUAL);
+INSERT INTO "odd ""name"""("a") VALUES('x;y');
+CREATE TABLE log (msg TEXT);
+INSERT INTO "log"("msg") VALUES('inserted; Alice');
+INSERT INTO "log"("msg") VALUES('inserted; O''Brien
+line 2');
+INSERT INTO "log"("msg") VALUES('inserted; Carol');
+DELETE FROM sqlite_sequence WHERE name = 'users';
+INSERT INTO sqlite_sequence(name,seq) VALUES('users',3);
+CREATE INDEX users_name ON users (name);
+CREATE TRIGGER users_log AFTER INSERT ON users BEGIN
+	INSERT INTO log (msg) VALUES ('inserted; ' || new.name);
+END;
+CREATE VIEW user_names AS SELECT name FROM users;
+CREATE TRIGGER user_names_insert INSTEAD OF INSERT ON user_names BEGIN
+	INSERT INTO users (name) VALUES (new.name);
+END;
+CREATE INDEX users_score ON users (score);
+COMMIT;
+`
+	isEq(t, want, dump)
+	// load into a fresh database and dump again
+	sq2 := testDumpSqinn(t)
+	isNoErr(t, sq2.LoadScript(strings.NewReader(dump)))
+	buf.Reset()
+	isNoErr(t, sq2.Dump(&buf, DumpOptions{}))
+	isEq(t, dump, buf.String())
+	rows := sq2.MustQueryRows("SELECT score FROM users WHERE id = 1", nil, []byte{ValDouble})
+	isEq(t, score, rows[0][0].Double)
+	sq2.MustExecSql("INSERT INTO user_names (name) VALUES ('Dave')")
+	rows = sq2.MustQueryRows("SELECT id FROM users WHERE name = 'Dave'", nil, []byte{ValInt32})
+	isEq(t, 4, rows[0][0].Int32)
+	// subset, schema only
+	buf.Reset()
+	isNoErr(t, sq.Dump(&buf, DumpOptions{Tables: []string{"users"}, SchemaOnly: true}))
+	want = `PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, score REAL, avatar BLOB);
+CREATE INDEX users_name ON users (name);
+CREATE TRIGGER users_log AFTER INSERT ON users BEGIN
+	INSERT INTO log (msg) VALUES ('inserted; ' || new.name);
+END;
+CREATE INDEX users_score ON users (score);
+COMMIT;
+`
+	isEq(t, want, buf.String())
+	// write error
+	err := sq.Dump(&failingWriter{n: 10}, DumpOptions{})
+	isErr(t, err, "disk full")
+	sq.MustExecSql("SELECT 1") // still usable, savepoint released
+}
+
+func TestDumpConcurrent(t *testing.T) {
+	sq := testDumpSqinn(t)
+	sq.MustExecSql("CREATE TABLE a (x)")
+	sq.MustExecSql("CREATE TABLE b (x)")
+	sq.MustExecSql("CREATE TRIGGER a_b AFTER INSERT ON a BEGIN INSERT INTO b VALUES (new.x); END")
+	sq.MustExecParams("INSERT INTO a VALUES (?)", 1000, 1, slices.Repeat([]Value{Int32Value(1)}, 1000))
+	// another goroutine writes to a and b while Dump runs
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if err := sq.ExecSql("INSERT INTO a VALUES (2)"); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	for range 20 {
+		var buf bytes.Buffer
+		isNoErr(t, sq.Dump(&buf, DumpOptions{}))
+		dump := buf.String()
+		isEq(t, strings.Count(dump, `INSERT INTO "a"`), strings.Count(dump, `INSERT INTO "b"`))
+	}
+	close(stop)
+	isNoErr(t, <-done)
+}
@@ -0,0 +1,93 @@
+package sqinn
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An ArgError describes an invalid argument of a method call, e.g.
+// a negative niterations in Exec or a parameter count that does not match
+// the SQL statement. Most invalid arguments are programming errors, so
+// by default methods panic instead of returning an ArgError, see
+// Options.NoPanic. Parameter count mismatches are always returned as
+// ArgError.
+type ArgError struct {
+	Method string // The method, e.g. "Exec".
+	Msg    string // What is wrong, e.g. "invalid niterations < 0".
+}
+
+func (e *ArgError) Error() string {
+	return e.Method + ": " + e.Msg
+}
+
+// ErrProtocol is wrapped by errors that report a malformed response from
+// sqinn. After such an error, the instance is broken, see ErrBroken.
+var ErrProtocol = errors.New("protocol error")
+
+// ErrBroken is wrapped by the errors of all calls to an instance after an
+// I/O error or a protocol error, or after a ProduceFunc or ConsumeFunc did
+// not return in the middle of a request, because the communication with
+// sqinn is in an unknown state. The errors also wrap the original cause. A
+// broken instance must be closed, Close kills the sqinn process.
+var ErrBroken = errors.New("sqinn: instance is broken")
+
+// ErrClosed is returned by calls to an instance after Close.
+var ErrClosed = errors.New("sqinn: instance is closed")
+
+// A ProtocolError describes a malformed response from sqinn, e.g. an
+// invalid value type or a string that exceeds Options.MaxStringSize.
+// It matches ErrProtocol in errors.Is.
+type ProtocolError struct {
+	Offset int64  // The byte offset of the malformed data in the response stream, starting at 0.
+	Msg    string // What is wrong.
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("protocol error at offset %d: %s", e.Offset, e.Msg)
+}
+
+func (e *ProtocolError) Is(target error) bool {
+	return target == ErrProtocol
+}
+
+// argError returns an *ArgError if the instance was launched with
+// Options.NoPanic, otherwise it panics with the message.
+func (sq *Sqinn) argError(method string, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if !sq.noPanic {
+		panic(msg)
+	}
+	return &ArgError{method, msg}
+}
+
+// fail marks the instance as broken because of err, so that all further
+// requests fail. It is called for errors that leave the communication with
+// sqinn in an unknown state, like I/O errors and malformed responses.
+// It returns err.
+func (sq *Sqinn) fail(err error) error {
+	if sq.broken == nil {
+		sq.broken = fmt.Errorf("%w: %w", ErrBroken, err)
+	}
+	return err
+}
+
+// usable returns ErrClosed if the instance is closed, the broken error if it
+// is broken, and nil otherwise. The caller must hold sq.mu.
+func (sq *Sqinn) usable() error {
+	if sq.closed {
+		return ErrClosed
+	}
+	return sq.broken
+}
+
+// abort abandons the current request because of err. If no part of the
+// request was sent to sqinn yet, it is discarded. Otherwise sqinn waits for
+// the rest of the request, and the instance is broken. The caller must hold
+// sq.mu.
+func (sq *Sqinn) abort(nflush int, err error) error {
+	if sq.w.nflush != nflush {
+		return sq.fail(err)
+	}
+	sq.w.wp = 0
+	return err
+}
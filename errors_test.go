@@ -0,0 +1,230 @@
+package sqinn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestArgError(t *testing.T) {
+	sq := MustLaunch(Options{NoPanic: true})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	consume := func(row int, values []Value) {}
+	produce := func(iteration int, params []Value) {}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{sq.Exec("DELETE FROM users", -1, 0, nil), "Exec: invalid niterations < 0"},
+		{sq.Exec("DELETE FROM users", 1, -1, nil), "Exec: invalid nparams < 0"},
+		{sq.Exec("DELETE FROM users WHERE id = ?", 1, 1, nil), "Exec: invalid nparams > 0 && produce == nil"},
+		{sq.Query("SELECT id FROM users", nil, nil, consume), "Query: no coltypes"},
+		{sq.Query("SELECT id FROM users", nil, []byte{ValInt64}, nil), "Query: no consume func"},
+		{sq.Query("SELECT id FROM users", nil, []byte{ValNull}, consume), "Query: coltype ValNull not allowed in Query"},
+		{sq.Query("SELECT id FROM users WHERE id = ?", []Value{NullValue()}, []byte{ValInt64}, consume), "Query: nil param not allowed in Query"},
+		{sq.Query("SELECT id FROM users WHERE id = ?", []Value{{Type: 42}}, []byte{ValInt64}, consume), "Query: unknown param value type 42"},
+		{sq.ExecParams("CREATE TABLE t (a)", -1, 0, nil), "ExecParams: invalid niterations < 0"},
+		{sq.ExecParams("DELETE FROM users", 0, -1, nil), "ExecParams: invalid nparams < 0"},
+		{sq.ExecParams("INSERT INTO users (id, name) VALUES (?, ?)", 2, 2, []Value{Int64Value(1), StringValue("a"), Int64Value(2), List(StringValue("b"))}), "ExecParams: iteration 1: unknown param value type 6"},
+		{sq.ExecReturning("DELETE FROM users RETURNING id", 1, 0, produce, nil, nil), "ExecReturning: no coltypes"},
+		{sq.ExecReturning("DELETE FROM users WHERE id = ? RETURNING id", 1, 0, produce, []byte{ValInt64}, func(iteration, row int, values []Value) {}), "ExecReturning: nparams is 0 but SQL has 1 parameters"},
+		{ignore(sq.ExecBatch("DELETE FROM users", -1, 0, nil, BatchOptions{})), "ExecBatch: invalid niterations < 0"},
+		{ignore(sq.ExecBatch("DELETE FROM users WHERE id = ?", 1, 2, produce, BatchOptions{})), "ExecBatch: nparams is 2 but SQL has 1 parameters"},
+		{sq.InsertRows("users", nil, nil, InsertOptions{}), "InsertRows: no columns"},
+		{sq.InsertRows("users", []string{"id"}, [][]Value{{}}, InsertOptions{}), "InsertRows: want 1 values in row 0 but have 0"},
+		{sq.InsertRows("users", []string{"id"}, nil, InsertOptions{Or: "NEVER"}), `InsertRows: invalid conflict resolution "NEVER"`},
+		{sq.InsertRows("users", []string{"id"}, nil, InsertOptions{UpdateColumns: []string{"name"}}), "InsertRows: UpdateColumns without ConflictColumns"},
+		{sq.InsertRows("users", []string{"id", "name"}, [][]Value{{Int64Value(1), StringValue("a")}}, InsertOptions{MaxVariables: 1}), "InsertRows: want at most 1 columns (MaxVariables) but have 2"},
+		{sq.Export(context.Background(), io.Discard, ExportCSV, "SELECT id FROM users", nil, ExportOptions{Coltypes: []byte{ValInt64}, Names: []string{"a", "b"}}), "Export: want 1 names but have 2"},
+		{sq.Export(context.Background(), io.Discard, 42, "SELECT id FROM users", nil, ExportOptions{Coltypes: []byte{ValInt64}}), "Export: invalid export format 42"},
+		{sq.QueryNDJSON(io.Discard, "SELECT id FROM users", nil, []byte{ValInt64}, nil), "QueryNDJSON: want 1 names but have 0"},
+	}
+	for _, tt := range tests {
+		var argErr *ArgError
+		isTrue(t, errors.As(tt.err, &argErr), "want *ArgError but have %T %v", tt.err, tt.err)
+		isErr(t, tt.err, tt.want)
+	}
+	// invalid requests are not sent, the instance can still be used
+	isNoErr(t, sq.ExecParams("INSERT INTO users (id, name) VALUES (?, ?)", 1, 2, []Value{Int64Value(1), StringValue("a")}))
+	rows := sq.MustQueryRows("SELECT id, name FROM users", nil, []byte{ValInt64, ValString})
+	isEq(t, 1, len(rows))
+	isEq(t, "a", rows[0][1].String)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM sqlite_master WHERE name = 't'", nil, []byte{ValInt32})
+	isEq(t, 0, rows[0][0].Int32)
+	// without NoPanic, invalid arguments panic
+	sq2 := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq2.Close())
+	})
+	isPanic(t, "iteration 0: unknown param value type 6", func() {
+		sq2.Exec("SELECT ?", 1, 1, func(iteration int, params []Value) {
+			params[0] = List(Int32Value(1))
+		})
+	})
+	isNoErr(t, sq2.ExecSql("SELECT 1"))
+	// parameter count mismatches are errors, also without NoPanic
+	err := sq2.ExecSql("SELECT ?")
+	var argErr *ArgError
+	isTrue(t, errors.As(err, &argErr), "want *ArgError but have %T", err)
+	isEq(t, "Exec", argErr.Method)
+	isEq(t, "nparams is 0 but SQL has 1 parameters", argErr.Msg)
+}
+
+func ignore[T any](_ T, err error) error {
+	return err
+}
+
+func TestBroken(t *testing.T) {
+	// a fake instance that receives the responses from a buffer
+	fake := func(responses ...[]byte) *Sqinn {
+		var rb bytes.Buffer
+		for _, resp := range responses {
+			rb.Write(encodeInt32(len(resp)))
+			rb.Write(resp)
+		}
+		return &Sqinn{w: newWriter(io.Discard), r: newReader(&rb)}
+	}
+	consume := func(row int, values []Value) {}
+	// invalid value type
+	sq := fake([]byte{1, 42})
+	err := sq.Query("SELECT 1", nil, []byte{ValInt32}, consume)
+	isErr(t, err, "protocol error at offset 5: invalid value type 42")
+	isTrue(t, errors.Is(err, ErrProtocol), "want ErrProtocol")
+	var protoErr *ProtocolError
+	isTrue(t, errors.As(err, &protoErr), "want *ProtocolError but have %T", err)
+	isEq(t, int64(5), protoErr.Offset)
+	err = sq.ExecSql("SELECT 1")
+	isErr(t, err, "sqinn: instance is broken: protocol error at offset 5: invalid value type 42")
+	isTrue(t, errors.Is(err, ErrBroken), "want ErrBroken")
+	isTrue(t, errors.Is(err, ErrProtocol), "want ErrProtocol")
+	err = sq.Query("SELECT 1", nil, []byte{ValInt32}, consume)
+	isErr(t, err, "sqinn: instance is broken: protocol error at offset 5: invalid value type 42")
+	// invalid row marker
+	sq = fake([]byte{7})
+	isErr(t, sq.Query("SELECT 1", nil, []byte{ValInt32}, consume), "protocol error at offset 4: invalid row marker 7")
+	isErr(t, sq.ExecSql("SELECT 1"), "sqinn: instance is broken: protocol error at offset 4: invalid row marker 7")
+	// invalid status
+	sq = fake([]byte{7})
+	isErr(t, sq.ExecSql("SELECT 1"), "protocol error at offset 4: invalid status 7")
+	isErr(t, sq.ExecSql("SELECT 1"), "sqinn: instance is broken: protocol error at offset 4: invalid status 7")
+	// I/O error
+	sq = fake()
+	isErr(t, sq.ExecSql("SELECT 1"), "EOF")
+	err = sq.ExecSql("SELECT 1")
+	isErr(t, err, "sqinn: instance is broken: EOF")
+	isTrue(t, errors.Is(err, ErrBroken), "want ErrBroken")
+	isTrue(t, errors.Is(err, io.EOF), "want io.EOF")
+	// an error response does not break the instance
+	errmsg := "no such table: x"
+	resp := append(append([]byte{0}, encodeInt32(len(errmsg)+1)...), errmsg+"\x00"...)
+	sq = fake(resp, []byte{1})
+	isErr(t, sq.ExecSql("SELECT 1"), "sqinn: no such table: x")
+	isNoErr(t, sq.ExecSql("SELECT 1"))
+}
+
+func TestBrokenPartialRequest(t *testing.T) {
+	sq := MustLaunch(Options{NoPanic: true})
+	// the first iterations are sent before the invalid value is produced
+	blob := []byte(strings.Repeat("x", 1024*1024))
+	err := sq.Exec("SELECT ?", 3, 1, func(iteration int, params []Value) {
+		params[0] = BlobValue(blob)
+		if iteration == 2 {
+			params[0] = List(Int32Value(1))
+		}
+	})
+	isErr(t, err, "Exec: iteration 2: unknown param value type 6")
+	err = sq.ExecSql("SELECT 1")
+	isErr(t, err, "sqinn: instance is broken: unknown param value type 6")
+	// Close does not hang, it reaps the child and removes the tempdir
+	err = sq.Close()
+	var exitErr *exec.ExitError
+	isTrue(t, errors.As(err, &exitErr), "want *exec.ExitError but have %v", err)
+	isTrue(t, sq.cmd.ProcessState != nil, "want sqinn process reaped")
+	_, err = os.Stat(sq.tempdir)
+	isTrue(t, os.IsNotExist(err), "want tempdir removed but have %v", err)
+	isEq(t, ErrClosed, sq.ExecSql("SELECT 1"))
+}
+
+func TestClosed(t *testing.T) {
+	sq := MustLaunch(Options{})
+	isNoErr(t, sq.Close())
+	isEq(t, ErrClosed, sq.ExecSql("SELECT 1"))
+	isEq(t, ErrClosed, sq.Exec("SELECT ?", 1, 1, func(iteration int, params []Value) {
+		params[0] = Int32Value(1)
+	}))
+	isEq(t, ErrClosed, sq.Query("SELECT 1", nil, []byte{ValInt32}, func(row int, values []Value) {}))
+	_, err := sq.QueryRows("SELECT 1", nil, []byte{ValInt32})
+	isEq(t, ErrClosed, err)
+	_, err = sq.Describe("SELECT 1")
+	isEq(t, ErrClosed, err)
+	isNoErr(t, sq.Close())
+}
+
+func TestBrokenPanic(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		sq.Close()
+	})
+	sq.MustExecSql("CREATE TABLE t (a)")
+	// nothing was sent yet, the request is discarded
+	isPanic(t, "boom", func() {
+		sq.Exec("INSERT INTO t VALUES (?)", 3, 1, func(iteration int, params []Value) {
+			if iteration == 1 {
+				panic("boom")
+			}
+			params[0] = Int32Value(iteration)
+		})
+	})
+	rows, err := sq.QueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isNoErr(t, err)
+	isEq(t, 0, rows[0][0].Int32)
+	// a part of the request was sent, the instance is broken
+	blob := []byte(strings.Repeat("x", 1024*1024))
+	isPanic(t, "boom", func() {
+		sq.Exec("INSERT INTO t VALUES (?)", 3, 1, func(iteration int, params []Value) {
+			if iteration == 2 {
+				panic("boom")
+			}
+			params[0] = BlobValue(blob)
+		})
+	})
+	_, err = sq.QueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isErr(t, err, "sqinn: instance is broken: produce func did not return")
+	isTrue(t, errors.Is(err, ErrBroken), "want ErrBroken")
+	// a panic while reading the response breaks the instance
+	sq2 := MustLaunch(Options{})
+	t.Cleanup(func() {
+		sq2.Close()
+	})
+	isPanic(t, "boom", func() {
+		sq2.Query("SELECT 1 UNION ALL SELECT 2", nil, []byte{ValInt32}, func(row int, values []Value) {
+			panic("boom")
+		})
+	})
+	err = sq2.ExecSql("SELECT 1")
+	isErr(t, err, "sqinn: instance is broken: consume func did not return")
+	// runtime.Goexit, e.g. from t.FailNow, breaks the instance, too
+	sq3 := MustLaunch(Options{})
+	t.Cleanup(func() {
+		sq3.Close()
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sq3.Query("SELECT 1 UNION ALL SELECT 2", nil, []byte{ValInt32}, func(row int, values []Value) {
+			runtime.Goexit()
+		})
+	}()
+	<-done
+	err = sq3.ExecSql("SELECT 1")
+	isErr(t, err, "sqinn: instance is broken: consume func did not return")
+}
@@ -0,0 +1,302 @@
+package sqinn
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExportFormat is the output format of Export.
+type ExportFormat int
+
+const (
+	// ExportCSV writes CSV like encoding/csv, with a header record that
+	// holds the column names. Records end with "\n", see
+	// ExportOptions.UseCRLF for the "\r\n" line endings of RFC 4180.
+	ExportCSV ExportFormat = iota
+
+	// ExportJSON writes a JSON array of objects, see QueryJSON.
+	ExportJSON
+
+	// ExportNDJSON writes newline-delimited JSON, see QueryNDJSON.
+	ExportNDJSON
+
+	// ExportMarkdown writes a Markdown table. Columns are not aligned,
+	// since that would require all rows to be held in memory.
+	ExportMarkdown
+)
+
+// BlobEncoding defines how Export encodes blobs.
+type BlobEncoding int
+
+const (
+	// BlobBase64 encodes blobs in standard base64, e.g. "AQI=".
+	BlobBase64 BlobEncoding = iota
+
+	// BlobHex encodes blobs in lower-case hex, e.g. "0102".
+	BlobHex
+)
+
+// ExportOptions control how Export writes result rows.
+type ExportOptions struct {
+	// Coltypes are the column types of the query. Required.
+	Coltypes []byte
+
+	// Names are the column names, one for each coltype. They are used in
+	// the CSV header, the Markdown table header, and as JSON object keys.
+	// Default is empty ("c1", "c2", etc.).
+	Names []string
+
+	// NoHeader omits the CSV header record.
+	// Default is false.
+	NoHeader bool
+
+	// Comma is the CSV field delimiter.
+	// Default is ','.
+	Comma rune
+
+	// UseCRLF terminates CSV records with "\r\n" instead of "\n".
+	// Default is false.
+	UseCRLF bool
+
+	// Null is the text for NULL values in CSV and Markdown. In JSON,
+	// NULL values are always null.
+	// Default is empty.
+	Null string
+
+	// Blob is the encoding of blobs.
+	// Default is BlobBase64.
+	Blob BlobEncoding
+}
+
+// Export executes a query and writes the result rows to w in the given format.
+//
+// Rows are streamed from sqinn to w, one row at a time, so memory usage is
+// constant regardless of the number of rows. If writing to w fails or ctx is
+// done, Export stops writing, still reads all remaining rows, and returns the
+// write error or ctx.Err() afterwards. If the query fails, the output written
+// so far is incomplete.
+func (sq *Sqinn) Export(ctx context.Context, w io.Writer, format ExportFormat, sql string, params []Value, opt ExportOptions) error {
+	names := opt.Names
+	if len(names) == 0 {
+		for i := range opt.Coltypes {
+			names = append(names, fmt.Sprintf("c%d", i+1))
+		}
+	}
+	if len(names) != len(opt.Coltypes) {
+		return sq.argError("Export", "want %d names but have %d", len(opt.Coltypes), len(names))
+	}
+	enc := &exporter{format: format, opt: opt}
+	switch format {
+	case ExportCSV:
+		enc.comma = ','
+		if opt.Comma != 0 {
+			enc.comma = opt.Comma
+		}
+		enc.eol = "\n"
+		if opt.UseCRLF {
+			enc.eol = "\r\n"
+		}
+	case ExportJSON, ExportNDJSON:
+		// precompute object keys: `{"id":`, `,"name":`, ...
+		enc.keys = make([][]byte, len(names))
+		for i, name := range names {
+			sep := byte(',')
+			if i == 0 {
+				sep = '{'
+			}
+			enc.keys[i] = append(appendJSONString([]byte{sep}, name), ':')
+		}
+	case ExportMarkdown:
+	default:
+		return sq.argError("Export", "invalid export format %d", format)
+	}
+	bw := bufio.NewWriter(w)
+	var writeErr error
+	write := func(p []byte) {
+		if writeErr == nil {
+			_, writeErr = bw.Write(p)
+		}
+	}
+	write(enc.begin(names))
+	err := sq.Query(sql, params, opt.Coltypes, func(row int, values []Value) {
+		if writeErr != nil {
+			return // keep draining rows
+		}
+		if writeErr = ctx.Err(); writeErr != nil {
+			return
+		}
+		write(enc.row(row, values))
+	})
+	if err != nil {
+		return err
+	}
+	write(enc.end())
+	if writeErr != nil {
+		return writeErr
+	}
+	return bw.Flush()
+}
+
+// MustExport is the same as Export except it panics on error.
+func (sq *Sqinn) MustExport(ctx context.Context, w io.Writer, format ExportFormat, sql string, params []Value, opt ExportOptions) {
+	must(0, sq.Export(ctx, w, format, sql, params, opt))
+}
+
+// exporter encodes result rows. All methods return a buffer that is only
+// valid until the next call.
+type exporter struct {
+	format ExportFormat
+	opt    ExportOptions
+	comma  rune     // CSV
+	eol    string   // CSV
+	keys   [][]byte // JSON
+	p      []byte
+}
+
+func (e *exporter) begin(names []string) []byte {
+	p := e.p[:0]
+	switch e.format {
+	case ExportCSV:
+		if !e.opt.NoHeader {
+			for i, name := range names {
+				if i > 0 {
+					p = utf8.AppendRune(p, e.comma)
+				}
+				p = e.appendCSVField(p, name)
+			}
+			p = append(p, e.eol...)
+		}
+	case ExportJSON:
+		p = append(p, '[')
+	case ExportMarkdown:
+		p = append(p, '|')
+		for _, name := range names {
+			p = append(p, ' ')
+			p = appendMarkdownCell(p, name)
+			p = append(p, " |"...)
+		}
+		p = append(p, "\n|"...)
+		for range names {
+			p = append(p, " --- |"...)
+		}
+		p = append(p, '\n')
+	}
+	e.p = p
+	return p
+}
+
+func (e *exporter) row(row int, values []Value) []byte {
+	p := e.p[:0]
+	switch e.format {
+	case ExportCSV:
+		for i, v := range values {
+			if i > 0 {
+				p = utf8.AppendRune(p, e.comma)
+			}
+			if v.Type == ValNull {
+				p = e.appendCSVField(p, e.opt.Null)
+			} else {
+				p = e.appendCSVField(p, e.text(v))
+			}
+		}
+		p = append(p, e.eol...)
+	case ExportJSON, ExportNDJSON:
+		if row > 0 && e.format == ExportJSON {
+			p = append(p, ',')
+		}
+		for i, v := range values {
+			p = append(p, e.keys[i]...)
+			if v.Type == ValBlob && e.opt.Blob == BlobHex {
+				p = append(p, '"')
+				p = hex.AppendEncode(p, v.Blob)
+				p = append(p, '"')
+			} else {
+				p = appendJSON(p, v)
+			}
+		}
+		p = append(p, '}')
+		if e.format == ExportNDJSON {
+			p = append(p, '\n')
+		}
+	case ExportMarkdown:
+		p = append(p, '|')
+		for _, v := range values {
+			p = append(p, ' ')
+			if v.Type == ValNull {
+				p = appendMarkdownCell(p, e.opt.Null)
+			} else {
+				p = appendMarkdownCell(p, e.text(v))
+			}
+			p = append(p, " |"...)
+		}
+		p = append(p, '\n')
+	}
+	e.p = p
+	return p
+}
+
+func (e *exporter) end() []byte {
+	if e.format == ExportJSON {
+		return []byte{']'}
+	}
+	return nil
+}
+
+// text returns the text of a non-NULL value for CSV and Markdown.
+func (e *exporter) text(v Value) string {
+	switch v.Type {
+	case ValInt32:
+		return strconv.Itoa(v.Int32)
+	case ValInt64:
+		return strconv.FormatInt(v.Int64, 10)
+	case ValDouble:
+		return strconv.FormatFloat(v.Double, 'g', -1, 64)
+	case ValString:
+		return v.String
+	case ValBlob:
+		if e.opt.Blob == BlobHex {
+			return hex.EncodeToString(v.Blob)
+		}
+		return base64.StdEncoding.EncodeToString(v.Blob)
+	}
+	return ""
+}
+
+// appendCSVField appends a CSV field to p. Fields that contain the
+// delimiter, a double quote, CR or LF are enclosed in double quotes.
+func (e *exporter) appendCSVField(p []byte, field string) []byte {
+	if !strings.ContainsRune(field, e.comma) && !strings.ContainsAny(field, "\"\r\n") {
+		return append(p, field...)
+	}
+	p = append(p, '"')
+	p = append(p, strings.ReplaceAll(field, `"`, `""`)...)
+	return append(p, '"')
+}
+
+// appendMarkdownCell appends a Markdown table cell to p. Pipes are
+// escaped and line breaks are replaced by <br>.
+func appendMarkdownCell(p []byte, cell string) []byte {
+	for i := 0; i < len(cell); i++ {
+		switch c := cell[i]; c {
+		case '|':
+			p = append(p, `\|`...)
+		case '\r':
+			if i+1 < len(cell) && cell[i+1] == '\n' {
+				i++
+			}
+			p = append(p, "<br>"...)
+		case '\n':
+			p = append(p, "<br>"...)
+		default:
+			p = append(p, c)
+		}
+	}
+	return p
+}
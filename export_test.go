@@ -0,0 +1,90 @@
+package sqinn
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL, data BLOB)")
+	sq.MustExecParams("INSERT INTO items (id, name, price, data) VALUES (?, ?, ?, ?)", 3, 4, []Value{
+		Int32Value(1), StringValue("plain"), DoubleValue(1.5), BlobValue([]byte{1, 2}),
+		Int32Value(2), StringValue("a,b \"c\"\nd|e"), NullValue(), NullValue(),
+		Int32Value(3), NullValue(), DoubleValue(-2), BlobValue([]byte{0xAB}),
+	})
+	ctx := context.Background()
+	sql := "SELECT id, name, price, data FROM items ORDER BY id"
+	opt := ExportOptions{
+		Coltypes: []byte{ValInt32, ValString, ValDouble, ValBlob},
+		Names:    []string{"id", "name", "price", "data"},
+	}
+	export := func(format ExportFormat, opt ExportOptions) string {
+		var buf bytes.Buffer
+		isNoErr(t, sq.Export(ctx, &buf, format, sql, nil, opt))
+		return buf.String()
+	}
+	// CSV
+	have := export(ExportCSV, opt)
+	isEq(t, "id,name,price,data\n"+
+		"1,plain,1.5,AQI=\n"+
+		"2,\"a,b \"\"c\"\"\nd|e\",,\n"+
+		"3,,-2,qw==\n", have)
+	records, err := csv.NewReader(bytes.NewBufferString(have)).ReadAll()
+	isNoErr(t, err)
+	isEq(t, 4, len(records))
+	isEq(t, "a,b \"c\"\nd|e", records[2][1])
+	csvOpt := opt
+	csvOpt.NoHeader = true
+	csvOpt.Comma = ';'
+	csvOpt.UseCRLF = true
+	csvOpt.Null = "NULL"
+	csvOpt.Blob = BlobHex
+	isEq(t, "1;plain;1.5;0102\r\n"+
+		"2;\"a,b \"\"c\"\"\nd|e\";NULL;NULL\r\n"+
+		"3;NULL;-2;ab\r\n", export(ExportCSV, csvOpt))
+	// JSON
+	isEq(t, `[{"id":1,"name":"plain","price":1.5,"data":"AQI="},`+
+		`{"id":2,"name":"a,b \"c\"\nd|e","price":null,"data":null},`+
+		`{"id":3,"name":null,"price":-2,"data":"qw=="}]`, export(ExportJSON, opt))
+	jsonOpt := opt
+	jsonOpt.Names = nil
+	jsonOpt.Blob = BlobHex
+	isEq(t, `{"c1":1,"c2":"plain","c3":1.5,"c4":"0102"}`+"\n"+
+		`{"c1":2,"c2":"a,b \"c\"\nd|e","c3":null,"c4":null}`+"\n"+
+		`{"c1":3,"c2":null,"c3":-2,"c4":"ab"}`+"\n", export(ExportNDJSON, jsonOpt))
+	// Markdown
+	mdOpt := opt
+	mdOpt.Null = "_null_"
+	isEq(t, "| id | name | price | data |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| 1 | plain | 1.5 | AQI= |\n"+
+		"| 2 | a,b \"c\"<br>d\\|e | _null_ | _null_ |\n"+
+		"| 3 | _null_ | -2 | qw== |\n", export(ExportMarkdown, mdOpt))
+	// no rows
+	var buf bytes.Buffer
+	isNoErr(t, sq.Export(ctx, &buf, ExportJSON, "SELECT id FROM items WHERE id < 0", nil, ExportOptions{Coltypes: []byte{ValInt32}}))
+	isEq(t, "[]", buf.String())
+	// errors
+	isPanic(t, "want 4 names but have 1", func() {
+		sq.Export(ctx, &buf, ExportCSV, sql, nil, ExportOptions{Coltypes: opt.Coltypes, Names: []string{"id"}})
+	})
+	isPanic(t, "invalid export format 99", func() {
+		sq.Export(ctx, &buf, ExportFormat(99), sql, nil, opt)
+	})
+	err = sq.Export(ctx, &failingWriter{n: 30}, ExportCSV, sql, nil, opt)
+	isErr(t, err, "disk full")
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = sq.Export(canceled, &buf, ExportCSV, sql, nil, opt)
+	isTrue(t, errors.Is(err, context.Canceled), "want context.Canceled but have %v", err)
+	// sqinn is still usable after errors
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM items", nil, []byte{ValInt32})
+	isEq(t, 3, rows[0][0].Int32)
+}
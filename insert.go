@@ -0,0 +1,179 @@
+package sqinn
+
+import (
+	"strings"
+)
+
+// InsertOptions control how InsertRows inserts rows.
+type InsertOptions struct {
+	// Or is the conflict resolution algorithm of the INSERT statement,
+	// one of "ABORT", "FAIL", "IGNORE", "REPLACE" or "ROLLBACK".
+	// It generates "INSERT OR <Or> INTO ...".
+	// Default is empty (plain "INSERT INTO ...").
+	Or string
+
+	// ConflictColumns is the conflict target of an upsert clause. If it is
+	// not empty, InsertRows generates "ON CONFLICT(<ConflictColumns>) DO ...".
+	// Default is empty (no upsert clause).
+	ConflictColumns []string
+
+	// UpdateColumns are the columns that are updated by an upsert clause,
+	// with "DO UPDATE SET col=excluded.col". If ConflictColumns is
+	// not empty but UpdateColumns is empty, the upsert clause is "DO NOTHING".
+	// UpdateColumns require ConflictColumns.
+	// Default is empty.
+	UpdateColumns []string
+
+	// MaxVariables is the maximum number of parameters in one statement,
+	// see SQLITE_MAX_VARIABLE_NUMBER. A row must not have more columns.
+	// Default is 32766.
+	MaxVariables int
+
+	// RowsPerStatement is the maximum number of rows that are inserted
+	// by one multi-row INSERT statement. It is further limited by MaxVariables.
+	// A value of 1 inserts one row per statement execution.
+	// Default is 64.
+	RowsPerStatement int
+}
+
+// InsertRows inserts rows into a table.
+//
+// Columns are the names of the columns to insert. Each row must have
+// exactly len(columns) values. Table and column names are quoted as
+// SQL identifiers.
+//
+// InsertRows builds multi-row "INSERT INTO table (columns) VALUES (...),(...)"
+// statements, chunked so that a statement never has more than opt.MaxVariables
+// parameters. All statements are executed inside one SAVEPOINT, so either all
+// rows are inserted or none. InsertRows holds the sqinn instance for all
+// statements, so no other calls are interleaved with its savepoint.
+func (sq *Sqinn) InsertRows(table string, columns []string, rows [][]Value, opt InsertOptions) error {
+	ncols := len(columns)
+	if ncols == 0 {
+		return sq.argError("InsertRows", "no columns")
+	}
+	for i, row := range rows {
+		if len(row) != ncols {
+			return sq.argError("InsertRows", "want %d values in row %d but have %d", ncols, i, len(row))
+		}
+	}
+	switch opt.Or {
+	case "", "ABORT", "FAIL", "IGNORE", "REPLACE", "ROLLBACK":
+	default:
+		return sq.argError("InsertRows", "invalid conflict resolution %q", opt.Or)
+	}
+	if len(opt.UpdateColumns) > 0 && len(opt.ConflictColumns) == 0 {
+		return sq.argError("InsertRows", "UpdateColumns without ConflictColumns")
+	}
+	maxVariables := opt.MaxVariables
+	if maxVariables <= 0 {
+		maxVariables = 32766
+	}
+	if ncols > maxVariables {
+		return sq.argError("InsertRows", "want at most %d columns (MaxVariables) but have %d", maxVariables, ncols)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	rowsPerStatement := opt.RowsPerStatement
+	if rowsPerStatement <= 0 {
+		rowsPerStatement = 64
+	}
+	rowsPerStatement = min(rowsPerStatement, maxVariables/ncols)
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if err := sq.execSql("SAVEPOINT sqinn_insert"); err != nil {
+		return err
+	}
+	if err := sq.insertRows(table, columns, rows, opt, rowsPerStatement); err != nil {
+		return sq.rollbackTo("sqinn_insert", err)
+	}
+	return sq.execSql("RELEASE sqinn_insert")
+}
+
+// MustInsertRows is the same as InsertRows except it panics on error.
+func (sq *Sqinn) MustInsertRows(table string, columns []string, rows [][]Value, opt InsertOptions) {
+	must(0, sq.InsertRows(table, columns, rows, opt))
+}
+
+// insertRows executes the INSERT statements. The caller must hold sq.mu.
+func (sq *Sqinn) insertRows(table string, columns []string, rows [][]Value, opt InsertOptions, rowsPerStatement int) error {
+	ncols := len(columns)
+	nstatements := len(rows) / rowsPerStatement
+	if nstatements > 0 {
+		sql := insertSql(table, columns, rowsPerStatement, opt)
+		nparams := rowsPerStatement * ncols
+		err := sq.exec("InsertRows", sql, nstatements, nparams, func(iteration int, params []Value) {
+			for i, row := range rows[iteration*rowsPerStatement : (iteration+1)*rowsPerStatement] {
+				copy(params[i*ncols:], row)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	rest := rows[nstatements*rowsPerStatement:]
+	if len(rest) > 0 {
+		sql := insertSql(table, columns, len(rest), opt)
+		err := sq.exec("InsertRows", sql, 1, len(rest)*ncols, func(iteration int, params []Value) {
+			for i, row := range rest {
+				copy(params[i*ncols:], row)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSql builds a INSERT statement that inserts nrows rows.
+func insertSql(table string, columns []string, nrows int, opt InsertOptions) string {
+	var sb strings.Builder
+	sb.WriteString("INSERT ")
+	if opt.Or != "" {
+		sb.WriteString("OR " + opt.Or + " ")
+	}
+	sb.WriteString("INTO " + quoteIdent(table) + " (")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(quoteIdent(col))
+	}
+	sb.WriteString(") VALUES ")
+	placeholders := "(?" + strings.Repeat(",?", len(columns)-1) + ")"
+	for i := range nrows {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(placeholders)
+	}
+	if len(opt.ConflictColumns) > 0 {
+		sb.WriteString(" ON CONFLICT(")
+		for i, col := range opt.ConflictColumns {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(quoteIdent(col))
+		}
+		sb.WriteString(") DO ")
+		if len(opt.UpdateColumns) == 0 {
+			sb.WriteString("NOTHING")
+		} else {
+			sb.WriteString("UPDATE SET ")
+			for i, col := range opt.UpdateColumns {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString(quoteIdent(col) + "=excluded." + quoteIdent(col))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// quoteIdent quotes a SQL identifier, e.g. a table or column name.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,187 @@
+package sqinn
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsertRows(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql(`CREATE TABLE "user list" (id INTEGER PRIMARY KEY NOT NULL, "the ""name""" TEXT, age INTEGER)`)
+	columns := []string{"id", `the "name"`, "age"}
+	queryUsers := func() [][]Value {
+		return sq.MustQueryRows(`SELECT id, "the ""name""", age FROM "user list" ORDER BY id`, nil, []byte{ValInt32, ValString, ValInt32})
+	}
+	// insert
+	var rows [][]Value
+	for i := range 10 {
+		rows = append(rows, []Value{Int32Value(i + 1), StringValue(fmt.Sprintf("User %d", i+1)), Int32Value(20 + i)})
+	}
+	rows[2][1] = NullValue()
+	isNoErr(t, sq.InsertRows("user list", columns, rows, InsertOptions{RowsPerStatement: 4}))
+	users := queryUsers()
+	isEq(t, 10, len(users))
+	isEq(t, 1, users[0][0].Int32)
+	isEq(t, "User 1", users[0][1].String)
+	isEq(t, 20, users[0][2].Int32)
+	isEq(t, ValNull, users[2][1].Type)
+	isEq(t, 10, users[9][0].Int32)
+	isEq(t, "User 10", users[9][1].String)
+	isEq(t, 29, users[9][2].Int32)
+	// no rows is a NO-OP
+	isNoErr(t, sq.InsertRows("user list", columns, nil, InsertOptions{}))
+	// insert is all or nothing
+	sq.MustExecSql(`DELETE FROM "user list"`)
+	err := sq.InsertRows("user list", columns, append(rows, rows[9]), InsertOptions{RowsPerStatement: 4})
+	isErr(t, err, `sqinn: UNIQUE constraint failed: user list.id`)
+	isEq(t, 0, len(queryUsers()))
+	// rollback errors are returned, too: OR ROLLBACK ends the savepoint
+	err = sq.InsertRows("user list", columns, append(rows, rows[9]), InsertOptions{Or: "ROLLBACK"})
+	isErr(t, err, "sqinn: UNIQUE constraint failed: user list.id\nsqinn: no such savepoint: sqinn_insert\nsqinn: no such savepoint: sqinn_insert")
+	isEq(t, 0, len(queryUsers()))
+	// MaxVariables limits rows per statement
+	isNoErr(t, sq.InsertRows("user list", columns, rows, InsertOptions{MaxVariables: 7}))
+	isEq(t, 10, len(queryUsers()))
+	// INSERT OR IGNORE
+	sq.MustInsertRows("user list", columns, [][]Value{
+		{Int32Value(1), StringValue("Ignored"), Int32Value(0)},
+		{Int32Value(11), StringValue("User 11"), Int32Value(30)},
+	}, InsertOptions{Or: "IGNORE"})
+	users = queryUsers()
+	isEq(t, 11, len(users))
+	isEq(t, "User 1", users[0][1].String)
+	isEq(t, "User 11", users[10][1].String)
+	// INSERT OR REPLACE
+	sq.MustInsertRows("user list", columns, [][]Value{
+		{Int32Value(1), StringValue("Replaced"), NullValue()},
+	}, InsertOptions{Or: "REPLACE"})
+	users = queryUsers()
+	isEq(t, "Replaced", users[0][1].String)
+	isEq(t, ValNull, users[0][2].Type)
+	// upsert DO UPDATE
+	sq.MustInsertRows("user list", columns, [][]Value{
+		{Int32Value(2), StringValue("Updated"), Int32Value(99)},
+		{Int32Value(12), StringValue("User 12"), Int32Value(31)},
+	}, InsertOptions{ConflictColumns: []string{"id"}, UpdateColumns: []string{"age"}})
+	users = queryUsers()
+	isEq(t, 12, len(users))
+	isEq(t, "User 2", users[1][1].String)
+	isEq(t, 99, users[1][2].Int32)
+	isEq(t, "User 12", users[11][1].String)
+	// upsert DO NOTHING
+	sq.MustInsertRows("user list", columns, [][]Value{
+		{Int32Value(3), StringValue("Nothing"), Int32Value(0)},
+	}, InsertOptions{ConflictColumns: []string{"id"}})
+	users = queryUsers()
+	isEq(t, ValNull, users[2][1].Type)
+	// invalid arguments
+	isPanic(t, "no columns", func() {
+		sq.InsertRows("user list", nil, rows, InsertOptions{})
+	})
+	isPanic(t, "want 3 values in row 1 but have 1", func() {
+		sq.InsertRows("user list", columns, [][]Value{rows[0], {Int32Value(1)}}, InsertOptions{})
+	})
+	isPanic(t, `invalid conflict resolution "IGNORE; DROP TABLE users"`, func() {
+		sq.InsertRows("user list", columns, rows, InsertOptions{Or: "IGNORE; DROP TABLE users"})
+	})
+	isPanic(t, "UpdateColumns without ConflictColumns", func() {
+		sq.InsertRows("user list", columns, rows, InsertOptions{UpdateColumns: []string{"name"}})
+	})
+	isPanic(t, "want at most 2 columns (MaxVariables) but have 3", func() {
+		sq.InsertRows("user list", columns, rows, InsertOptions{MaxVariables: 2})
+	})
+}
+
+func TestInsertRowsConcurrent(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY NOT NULL)")
+	sq.MustExecSql("CREATE TABLE logs (id INTEGER PRIMARY KEY NOT NULL)")
+	// another goroutine writes while InsertRows rolls back
+	stop := make(chan struct{})
+	done := make(chan error)
+	nlogs := 0
+	go func() {
+		for {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if err := sq.ExecParams("INSERT INTO logs (id) VALUES(?)", 1, 1, []Value{Int32Value(nlogs)}); err != nil {
+				done <- err
+				return
+			}
+			nlogs++
+		}
+	}()
+	rows := [][]Value{{Int32Value(1)}, {Int32Value(2)}, {Int32Value(1)}}
+	for range 200 {
+		err := sq.InsertRows("users", []string{"id"}, rows, InsertOptions{RowsPerStatement: 1})
+		isErr(t, err, "sqinn: UNIQUE constraint failed: users.id")
+	}
+	close(stop)
+	isNoErr(t, <-done)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM logs", nil, []byte{ValInt32})
+	isEq(t, nlogs, rows[0][0].Int32)
+}
+
+func TestInsertSql(t *testing.T) {
+	isEq(t, `INSERT INTO "users" ("id","name") VALUES (?,?),(?,?)`, insertSql("users", []string{"id", "name"}, 2, InsertOptions{}))
+	isEq(t, `INSERT OR IGNORE INTO "users" ("id") VALUES (?)`, insertSql("users", []string{"id"}, 1, InsertOptions{Or: "IGNORE"}))
+	isEq(t, `INSERT INTO "users" ("id","name") VALUES (?,?) ON CONFLICT("id") DO NOTHING`,
+		insertSql("users", []string{"id", "name"}, 1, InsertOptions{ConflictColumns: []string{"id"}}))
+	isEq(t, `INSERT INTO "users" ("id","name","age") VALUES (?,?,?) ON CONFLICT("id") DO UPDATE SET "name"=excluded."name","age"=excluded."age"`,
+		insertSql("users", []string{"id", "name", "age"}, 1, InsertOptions{ConflictColumns: []string{"id"}, UpdateColumns: []string{"name", "age"}}))
+	isEq(t, `"a""b"`, quoteIdent(`a"b`))
+}
+
+func BenchmarkInsertRows(b *testing.B) {
+	const nusers = 10_000
+	columns := []string{"id", "name", "age"}
+	rows := make([][]Value, nusers)
+	for i := range rows {
+		rows[i] = []Value{Int32Value(i + 1), StringValue(fmt.Sprintf("User %d", i+1)), Int32Value(i % 100)}
+	}
+	launch := func(b *testing.B) *Sqinn {
+		sq := MustLaunch(Options{Db: filepath.Join(b.TempDir(), "test.db")})
+		b.Cleanup(func() {
+			if err := sq.Close(); err != nil {
+				b.Fatal(err)
+			}
+		})
+		sq.MustExecSql("CREATE TABLE users (id INTEGER NOT NULL PRIMARY KEY, name TEXT, age INTEGER)")
+		return sq
+	}
+	b.Run("ExecParams", func(b *testing.B) {
+		sq := launch(b)
+		b.ResetTimer()
+		for range b.N {
+			sq.MustExecSql("DELETE FROM users")
+			sq.MustExecSql("BEGIN IMMEDIATE")
+			params := make([]Value, 0, nusers*len(columns))
+			for _, row := range rows {
+				params = append(params, row...)
+			}
+			sq.MustExecParams("INSERT INTO users (id,name,age) VALUES (?,?,?)", nusers, len(columns), params)
+			sq.MustExecSql("COMMIT")
+		}
+	})
+	for _, rowsPerStatement := range []int{1, 16, 64, 256, 1024} {
+		b.Run(fmt.Sprintf("InsertRows%d", rowsPerStatement), func(b *testing.B) {
+			sq := launch(b)
+			b.ResetTimer()
+			for range b.N {
+				sq.MustExecSql("DELETE FROM users")
+				sq.MustInsertRows("users", columns, rows, InsertOptions{RowsPerStatement: rowsPerStatement})
+			}
+		})
+	}
+}
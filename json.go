@@ -0,0 +1,485 @@
+package sqinn
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format implements fmt.Formatter. The verbs %v and %s print a value
+// in a SQL-like notation, e.g. NULL, 42, 1.5, "text" or x'0102'.
+// The verb %#v prints a value as Go syntax, e.g. sqinn.Int32Value(42).
+//
+// Since Value has a field named String, it cannot implement fmt.Stringer.
+func (v Value) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		io.WriteString(f, v.goString())
+		return
+	}
+	io.WriteString(f, v.sqlString())
+}
+
+func (v Value) sqlString() string {
+	switch v.Type {
+	case ValNull:
+		return "NULL"
+	case ValInt32:
+		return strconv.Itoa(v.Int32)
+	case ValInt64:
+		return strconv.FormatInt(v.Int64, 10)
+	case ValDouble:
+		return strconv.FormatFloat(v.Double, 'g', -1, 64)
+	case ValString:
+		return strconv.Quote(v.String)
+	case ValBlob:
+		return "x'" + hex.EncodeToString(v.Blob) + "'"
+	case ValList:
+		parts := make([]string, len(v.List))
+		for i, item := range v.List {
+			parts[i] = item.sqlString()
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	}
+	return fmt.Sprintf("<invalid value type %d>", v.Type)
+}
+
+func (v Value) goString() string {
+	switch v.Type {
+	case ValNull:
+		return "sqinn.NullValue()"
+	case ValInt32:
+		return fmt.Sprintf("sqinn.Int32Value(%d)", v.Int32)
+	case ValInt64:
+		return fmt.Sprintf("sqinn.Int64Value(%d)", v.Int64)
+	case ValDouble:
+		return fmt.Sprintf("sqinn.DoubleValue(%#v)", v.Double)
+	case ValString:
+		return fmt.Sprintf("sqinn.StringValue(%q)", v.String)
+	case ValBlob:
+		return fmt.Sprintf("sqinn.BlobValue(%#v)", v.Blob)
+	case ValList:
+		parts := make([]string, len(v.List))
+		for i, item := range v.List {
+			parts[i] = item.goString()
+		}
+		return "sqinn.List(" + strings.Join(parts, ", ") + ")"
+	}
+	return fmt.Sprintf("sqinn.Value{Type: %d}", v.Type)
+}
+
+// MarshalJSON implements json.Marshaler. The JSON encoding preserves the
+// value type, so that all values survive a JSON round-trip:
+//
+//	ValNull  -> null
+//	ValInt32  -> {"int32":42}
+//	ValInt64  -> {"int64":"42"}
+//	ValDouble  -> {"double":1.5}, or {"double":"NaN"} for NaN and infinities
+//	ValString  -> {"string":"text"}
+//	ValBlob  -> {"blob":"AQI="} (base64)
+//	ValList  -> {"list":[...]}
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.Type {
+	case ValNull:
+		return []byte("null"), nil
+	case ValInt32:
+		return []byte(`{"int32":` + strconv.Itoa(v.Int32) + `}`), nil
+	case ValInt64:
+		return []byte(`{"int64":"` + strconv.FormatInt(v.Int64, 10) + `"}`), nil
+	case ValDouble:
+		if math.IsNaN(v.Double) || math.IsInf(v.Double, 0) {
+			return []byte(`{"double":"` + strconv.FormatFloat(v.Double, 'g', -1, 64) + `"}`), nil
+		}
+		return []byte(`{"double":` + strconv.FormatFloat(v.Double, 'g', -1, 64) + `}`), nil
+	case ValString:
+		data, err := json.Marshal(v.String)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(`{"string":` + string(data) + `}`), nil
+	case ValBlob:
+		return []byte(`{"blob":"` + base64.StdEncoding.EncodeToString(v.Blob) + `"}`), nil
+	case ValList:
+		data, err := json.Marshal(v.List)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(`{"list":` + string(data) + `}`), nil
+	}
+	return nil, fmt.Errorf("invalid value type %d", v.Type)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see MarshalJSON for the format.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = NullValue()
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m) != 1 {
+		return fmt.Errorf("invalid JSON value %s", data)
+	}
+	for key, raw := range m {
+		switch key {
+		case "int32":
+			var i int64
+			if err := json.Unmarshal(raw, &i); err != nil {
+				return err
+			}
+			*v = Int32Value(int(i))
+			return nil
+		case "int64":
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			*v = Int64Value(i)
+			return nil
+		case "double":
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				f, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return err
+				}
+				*v = DoubleValue(f)
+				return nil
+			}
+			var f float64
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return err
+			}
+			*v = DoubleValue(f)
+			return nil
+		case "string":
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return err
+			}
+			*v = StringValue(s)
+			return nil
+		case "blob":
+			var b []byte
+			if err := json.Unmarshal(raw, &b); err != nil {
+				return err
+			}
+			if b == nil {
+				b = []byte{}
+			}
+			*v = BlobValue(b)
+			return nil
+		case "list":
+			var list []Value
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return err
+			}
+			*v = List(list...)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid JSON value %s", data)
+}
+
+// MarshalText implements encoding.TextMarshaler. The text encoding preserves
+// the value type: "null", "int32:42", "int64:42", "double:1.5",
+// "string:text" or "blob:AQI=" (base64). Lists cannot be encoded as text.
+func (v Value) MarshalText() ([]byte, error) {
+	switch v.Type {
+	case ValNull:
+		return []byte("null"), nil
+	case ValInt32:
+		return []byte("int32:" + strconv.Itoa(v.Int32)), nil
+	case ValInt64:
+		return []byte("int64:" + strconv.FormatInt(v.Int64, 10)), nil
+	case ValDouble:
+		return []byte("double:" + strconv.FormatFloat(v.Double, 'g', -1, 64)), nil
+	case ValString:
+		return []byte("string:" + v.String), nil
+	case ValBlob:
+		return []byte("blob:" + base64.StdEncoding.EncodeToString(v.Blob)), nil
+	}
+	return nil, fmt.Errorf("cannot encode value type %d as text", v.Type)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, see MarshalText for the format.
+func (v *Value) UnmarshalText(text []byte) error {
+	s := string(text)
+	if s == "null" {
+		*v = NullValue()
+		return nil
+	}
+	typ, data, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("invalid text value %q", s)
+	}
+	switch typ {
+	case "int32":
+		i, err := strconv.ParseInt(data, 10, 32)
+		if err != nil {
+			return err
+		}
+		*v = Int32Value(int(i))
+	case "int64":
+		i, err := strconv.ParseInt(data, 10, 64)
+		if err != nil {
+			return err
+		}
+		*v = Int64Value(i)
+	case "double":
+		f, err := strconv.ParseFloat(data, 64)
+		if err != nil {
+			return err
+		}
+		*v = DoubleValue(f)
+	case "string":
+		*v = StringValue(data)
+	case "blob":
+		b, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		*v = BlobValue(b)
+	default:
+		return fmt.Errorf("invalid text value %q", s)
+	}
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder. It is needed because gob would
+// otherwise use MarshalText, which cannot encode lists.
+func (v Value) GobEncode() ([]byte, error) {
+	return appendBinaryValue(nil, v), nil
+}
+
+// GobDecode implements gob.GobDecoder.
+func (v *Value) GobDecode(data []byte) error {
+	value, rest, err := decodeBinaryValue(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("gob: %d trailing bytes", len(rest))
+	}
+	*v = value
+	return nil
+}
+
+// appendBinaryValue appends the binary encoding of v to p. The encoding
+// is the sqinn wire encoding, plus lists.
+func appendBinaryValue(p []byte, v Value) []byte {
+	p = append(p, v.Type)
+	switch v.Type {
+	case ValInt32:
+		p = append(p, encodeInt64(int64(v.Int32))...)
+	case ValInt64:
+		p = append(p, encodeInt64(v.Int64)...)
+	case ValDouble:
+		p = append(p, encodeDouble(v.Double)...)
+	case ValString:
+		p = append(p, encodeInt64(int64(len(v.String)))...)
+		p = append(p, v.String...)
+	case ValBlob:
+		p = append(p, encodeInt64(int64(len(v.Blob)))...)
+		p = append(p, v.Blob...)
+	case ValList:
+		p = append(p, encodeInt64(int64(len(v.List)))...)
+		for _, item := range v.List {
+			p = appendBinaryValue(p, item)
+		}
+	}
+	return p
+}
+
+var errShortBinaryValue = errors.New("gob: short value")
+
+// decodeBinaryValue decodes a value encoded by appendBinaryValue.
+// It returns the value and the remaining bytes.
+func decodeBinaryValue(p []byte) (Value, []byte, error) {
+	if len(p) < 1 {
+		return Value{}, nil, errShortBinaryValue
+	}
+	vt := p[0]
+	p = p[1:]
+	if vt == ValNull {
+		return NullValue(), p, nil
+	}
+	if len(p) < 8 {
+		return Value{}, nil, errShortBinaryValue
+	}
+	n := decodeInt64(p)
+	p = p[8:]
+	switch vt {
+	case ValInt32:
+		return Int32Value(int(n)), p, nil
+	case ValInt64:
+		return Int64Value(n), p, nil
+	case ValDouble:
+		return DoubleValue(math.Float64frombits(uint64(n))), p, nil
+	case ValString, ValBlob:
+		if n < 0 || n > int64(len(p)) {
+			return Value{}, nil, errShortBinaryValue
+		}
+		if vt == ValString {
+			return StringValue(string(p[:n])), p[n:], nil
+		}
+		return BlobValue(append([]byte{}, p[:n]...)), p[n:], nil
+	case ValList:
+		if n < 0 || n > int64(len(p)) {
+			return Value{}, nil, errShortBinaryValue
+		}
+		list := make([]Value, n)
+		for i := range list {
+			var err error
+			list[i], p, err = decodeBinaryValue(p)
+			if err != nil {
+				return Value{}, nil, err
+			}
+		}
+		return List(list...), p, nil
+	}
+	return Value{}, nil, fmt.Errorf("gob: invalid value type %d", vt)
+}
+
+// appendJSON appends the plain JSON encoding of v to p. Unlike MarshalJSON,
+// the plain encoding does not preserve the value type: integers and doubles
+// are JSON numbers, strings are JSON strings, blobs are base64 encoded JSON
+// strings, and NULL, NaN and infinities are JSON null.
+func appendJSON(p []byte, v Value) []byte {
+	switch v.Type {
+	case ValInt32:
+		return strconv.AppendInt(p, int64(v.Int32), 10)
+	case ValInt64:
+		return strconv.AppendInt(p, v.Int64, 10)
+	case ValDouble:
+		if math.IsNaN(v.Double) || math.IsInf(v.Double, 0) {
+			return append(p, "null"...)
+		}
+		return strconv.AppendFloat(p, v.Double, 'g', -1, 64)
+	case ValString:
+		return appendJSONString(p, v.String)
+	case ValBlob:
+		p = append(p, '"')
+		p = base64.StdEncoding.AppendEncode(p, v.Blob)
+		return append(p, '"')
+	case ValList:
+		p = append(p, '[')
+		for i, item := range v.List {
+			if i > 0 {
+				p = append(p, ',')
+			}
+			p = appendJSON(p, item)
+		}
+		return append(p, ']')
+	}
+	return append(p, "null"...)
+}
+
+// appendJSONString appends s as JSON string to p, with the same escapes as
+// json.Marshal, except that it does not escape HTML characters. It does
+// not allocate if p has enough capacity.
+func appendJSONString(p []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+	p = append(p, '"')
+	start := 0 // start of the bytes that need no escaping
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			p = append(p, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				p = append(p, '\\', c)
+			case '\b':
+				p = append(p, '\\', 'b')
+			case '\f':
+				p = append(p, '\\', 'f')
+			case '\n':
+				p = append(p, '\\', 'n')
+			case '\r':
+				p = append(p, '\\', 'r')
+			case '\t':
+				p = append(p, '\\', 't')
+			default:
+				p = append(p, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// invalid UTF-8
+			p = append(p, s[start:i]...)
+			p = append(p, "\ufffd"...)
+			start = i + size
+		case r == '\u2028' || r == '\u2029':
+			// valid JSON, but not valid JavaScript
+			p = append(p, s[start:i]...)
+			p = append(p, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			start = i + size
+		}
+		i += size
+	}
+	p = append(p, s[start:]...)
+	return append(p, '"')
+}
+
+// WriteRowsJSON writes rows as a compact JSON array of arrays, e.g.
+// [[1,"Alice"],[2,null]]. Values are encoded as plain JSON, which does
+// not preserve value types: blobs are base64 strings, and NULL, NaN and
+// infinities are null.
+func WriteRowsJSON(w io.Writer, rows [][]Value) error {
+	bw := bufio.NewWriter(w)
+	var p []byte
+	bw.WriteByte('[')
+	for irow, row := range rows {
+		if irow > 0 {
+			bw.WriteByte(',')
+		}
+		p = appendJSONRow(p[:0], row)
+		bw.Write(p)
+	}
+	bw.WriteByte(']')
+	return bw.Flush()
+}
+
+// WriteRowsNDJSON writes rows as newline-delimited JSON, one JSON array per
+// row and line. Values are encoded like in WriteRowsJSON.
+func WriteRowsNDJSON(w io.Writer, rows [][]Value) error {
+	bw := bufio.NewWriter(w)
+	var p []byte
+	for _, row := range rows {
+		p = appendJSONRow(p[:0], row)
+		p = append(p, '\n')
+		bw.Write(p)
+	}
+	return bw.Flush()
+}
+
+func appendJSONRow(p []byte, row []Value) []byte {
+	p = append(p, '[')
+	for i, v := range row {
+		if i > 0 {
+			p = append(p, ',')
+		}
+		p = appendJSON(p, v)
+	}
+	return append(p, ']')
+}
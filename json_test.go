@@ -0,0 +1,164 @@
+package sqinn
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueFormat(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  string
+		goStr string
+	}{
+		{NullValue(), "NULL", "sqinn.NullValue()"},
+		{Int32Value(42), "42", "sqinn.Int32Value(42)"},
+		{Int64Value(-7), "-7", "sqinn.Int64Value(-7)"},
+		{DoubleValue(1.5), "1.5", "sqinn.DoubleValue(1.5)"},
+		{StringValue("a\"b"), `"a\"b"`, `sqinn.StringValue("a\"b")`},
+		{BlobValue([]byte{1, 0xAB}), "x'01ab'", "sqinn.BlobValue([]byte{0x1, 0xab})"},
+		{List(Int32Value(1), NullValue()), "(1, NULL)", "sqinn.List(sqinn.Int32Value(1), sqinn.NullValue())"},
+	}
+	for _, tt := range tests {
+		isEq(t, tt.want, fmt.Sprintf("%v", tt.value))
+		isEq(t, tt.want, fmt.Sprintf("%s", tt.value))
+		isEq(t, tt.goStr, fmt.Sprintf("%#v", tt.value))
+	}
+	isEq(t, "[1 NULL]", fmt.Sprint([]Value{Int32Value(1), NullValue()}))
+}
+
+func testValues() []Value {
+	return []Value{
+		NullValue(),
+		Int32Value(-42),
+		Int64Value(math.MaxInt64),
+		DoubleValue(1.5),
+		DoubleValue(math.Inf(-1)),
+		StringValue("hello \"world\"\n"),
+		StringValue(""),
+		BlobValue([]byte{0, 1, 2, 0xFF}),
+		BlobValue([]byte{}),
+		List(Int32Value(1), StringValue("x"), List(NullValue())),
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	data, err := json.Marshal(testValues())
+	isNoErr(t, err)
+	want := `[null,{"int32":-42},{"int64":"9223372036854775807"},{"double":1.5},{"double":"-Inf"},` +
+		`{"string":"hello \"world\"\n"},{"string":""},{"blob":"AAEC/w=="},{"blob":""},` +
+		`{"list":[{"int32":1},{"string":"x"},{"list":[null]}]}]`
+	isEq(t, want, string(data))
+	var values []Value
+	isNoErr(t, json.Unmarshal(data, &values))
+	isTrue(t, reflect.DeepEqual(testValues(), values), "have %v", values)
+	// NaN
+	data, err = json.Marshal(DoubleValue(math.NaN()))
+	isNoErr(t, err)
+	isEq(t, `{"double":"NaN"}`, string(data))
+	var v Value
+	isNoErr(t, json.Unmarshal(data, &v))
+	isTrue(t, math.IsNaN(v.Double), "want NaN but have %v", v)
+	// errors
+	isErr(t, json.Unmarshal([]byte(`{"int32":1,"int64":"2"}`), &v), `invalid JSON value {"int32":1,"int64":"2"}`)
+	isErr(t, json.Unmarshal([]byte(`{"foo":1}`), &v), `invalid JSON value {"foo":1}`)
+	isErr(t, json.Unmarshal([]byte(`{"int64":"x"}`), &v), `strconv.ParseInt: parsing "x": invalid syntax`)
+	_, err = json.Marshal(Value{Type: 99})
+	isErr(t, err, "json: error calling MarshalJSON for type *sqinn.Value: invalid value type 99")
+}
+
+func TestValueText(t *testing.T) {
+	for _, value := range testValues() {
+		if value.Type == ValList {
+			_, err := value.MarshalText()
+			isErr(t, err, "cannot encode value type 6 as text")
+			continue
+		}
+		text, err := value.MarshalText()
+		isNoErr(t, err)
+		var v Value
+		isNoErr(t, v.UnmarshalText(text))
+		isTrue(t, reflect.DeepEqual(value, v), "want %v but have %v", value, v)
+	}
+	text, _ := Int64Value(12).MarshalText()
+	isEq(t, "int64:12", string(text))
+	text, _ = StringValue("a:b").MarshalText()
+	isEq(t, "string:a:b", string(text))
+	var v Value
+	isErr(t, v.UnmarshalText([]byte("foo")), `invalid text value "foo"`)
+	isErr(t, v.UnmarshalText([]byte("foo:1")), `invalid text value "foo:1"`)
+	isErr(t, v.UnmarshalText([]byte("int32:3000000000")), `strconv.ParseInt: parsing "3000000000": value out of range`)
+}
+
+func TestValueGob(t *testing.T) {
+	var buf bytes.Buffer
+	isNoErr(t, gob.NewEncoder(&buf).Encode(testValues()))
+	var values []Value
+	isNoErr(t, gob.NewDecoder(&buf).Decode(&values))
+	isTrue(t, reflect.DeepEqual(testValues(), values), "have %v", values)
+	var v Value
+	isErr(t, v.GobDecode([]byte{ValString, 0, 0, 0, 0, 0, 0, 0, 5, 'a'}), "gob: short value")
+	isErr(t, v.GobDecode([]byte{ValNull, 0}), "gob: 1 trailing bytes")
+	isErr(t, v.GobDecode([]byte{99, 0, 0, 0, 0, 0, 0, 0, 0}), "gob: invalid value type 99")
+}
+
+func TestWriteRows(t *testing.T) {
+	rows := [][]Value{
+		{Int32Value(1), StringValue("Alice"), DoubleValue(1.5), BlobValue([]byte{1, 2})},
+		{Int64Value(2), NullValue(), DoubleValue(math.NaN()), StringValue("<&>")},
+	}
+	var buf bytes.Buffer
+	isNoErr(t, WriteRowsJSON(&buf, rows))
+	isEq(t, `[[1,"Alice",1.5,"AQI="],[2,null,null,"<&>"]]`, buf.String())
+	buf.Reset()
+	isNoErr(t, WriteRowsNDJSON(&buf, rows))
+	isEq(t, "[1,\"Alice\",1.5,\"AQI=\"]\n[2,null,null,\"<&>\"]\n", buf.String())
+	buf.Reset()
+	isNoErr(t, WriteRowsJSON(&buf, nil))
+	isEq(t, `[]`, buf.String())
+}
+
+// marshalJSONString encodes s like json.Marshal, without HTML escapes.
+func marshalJSONString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func FuzzAppendJSONString(f *testing.F) {
+	var all []byte
+	for c := range 256 {
+		all = append(all, byte(c))
+	}
+	f.Add(string(all))
+	f.Add("plain <html> & \"quotes\" \\    äöü 😀")
+	f.Add("\xff\xfe\xc3\x28\xe2\x82")
+	f.Fuzz(func(t *testing.T, s string) {
+		isEq(t, marshalJSONString(s), string(appendJSONString(nil, s)))
+	})
+}
+
+func TestAppendJSONStringAllocs(t *testing.T) {
+	p := make([]byte, 0, 100)
+	allocs := testing.AllocsPerRun(100, func() {
+		p = appendJSONString(p[:0], "a \"b\"\n \xff")
+	})
+	isEq(t, 0.0, allocs)
+}
+
+func BenchmarkAppendJSONString(b *testing.B) {
+	s := strings.Repeat("Alice \"Al\" Smith\n", 4)
+	var p []byte
+	b.ReportAllocs()
+	for range b.N {
+		p = appendJSONString(p[:0], s)
+	}
+}
@@ -0,0 +1,182 @@
+package sqinn
+
+import "strconv"
+
+// tokenKind is the kind of a SQL token.
+type tokenKind int
+
+const (
+	tokSpace     tokenKind = iota // whitespace
+	tokComment                    // "-- comment" or "/* comment */"
+	tokString                     // 'string' or X'blob'
+	tokIdent                      // keyword or identifier, also "quoted", [quoted] and `quoted`
+	tokNumber                     // numeric literal
+	tokParam                      // parameter: ?, ?NNN, :AAA, @AAA or $AAA
+	tokSemicolon                  // ;
+	tokOther                      // operators and punctuation
+)
+
+// A token is a SQL token.
+type token struct {
+	kind tokenKind
+	pos  int    // byte offset in the SQL text
+	text string // token text
+}
+
+// A lexer splits SQL text into tokens. It follows the SQLite tokenizer
+// closely enough to find string literals, comments, identifiers and
+// parameters, but it does not validate the SQL. Unterminated
+// strings and comments extend to the end of the SQL text.
+type lexer struct {
+	sql string
+	pos int
+	// resume, if not 0, is an offset inside the string, quoted identifier
+	// or comment that starts at pos, up to which it is known to be not
+	// terminated, see splitter. The character before resume is not a quote.
+	resume int
+}
+
+// next returns the next token, or false at the end of the SQL text.
+func (l *lexer) next() (token, bool) {
+	sql := l.sql
+	start := l.pos
+	if start >= len(sql) {
+		return token{}, false
+	}
+	resume := l.resume
+	l.resume = 0
+	kind := tokOther
+	i := start
+	c := sql[i]
+	switch {
+	case isSpace(c):
+		kind = tokSpace
+		for i < len(sql) && isSpace(sql[i]) {
+			i++
+		}
+	case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+		kind = tokComment
+		for i < len(sql) && sql[i] != '\n' {
+			i++
+		}
+	case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+		kind = tokComment
+		i = max(i+2, resume-2)
+		for i < len(sql) && !(sql[i] == '*' && i+1 < len(sql) && sql[i+1] == '/') {
+			i++
+		}
+		i = min(i+2, len(sql))
+	case c == '\'':
+		kind = tokString
+		i = skipQuoted(sql, max(i, resume-1), '\'')
+	case (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+		kind = tokString
+		i = skipQuoted(sql, max(i+1, resume-1), '\'')
+	case c == '"' || c == '`':
+		kind = tokIdent
+		i = skipQuoted(sql, max(i, resume-1), c)
+	case c == '[':
+		kind = tokIdent
+		i = max(i, resume-1)
+		for i < len(sql) && sql[i] != ']' {
+			i++
+		}
+		i = min(i+1, len(sql))
+	case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+		kind = tokNumber
+		hex := c == '0' && i+1 < len(sql) && (sql[i+1] == 'x' || sql[i+1] == 'X')
+		for i < len(sql) && (isIdentChar(sql[i]) || sql[i] == '.' ||
+			(!hex && (sql[i] == '+' || sql[i] == '-') && (sql[i-1] == 'e' || sql[i-1] == 'E'))) {
+			i++
+		}
+	case isIdentChar(c) && c != '$':
+		kind = tokIdent
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+	case c == '?':
+		kind = tokParam
+		i++
+		for i < len(sql) && isDigit(sql[i]) {
+			i++
+		}
+	case (c == ':' || c == '@' || c == '$') && i+1 < len(sql) && isIdentChar(sql[i+1]):
+		kind = tokParam
+		i++
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+	case c == ';':
+		kind = tokSemicolon
+		i++
+	default:
+		i++
+	}
+	l.pos = i
+	return token{kind, start, sql[start:i]}, true
+}
+
+// countParams returns the number of parameters of the first statement in
+// sql, like sqlite3_bind_parameter_count. It is the largest parameter
+// index: ? takes the largest index so far plus one, ?NNN takes index NNN,
+// and a named parameter takes the index of its first occurrence.
+func countParams(sql string) int {
+	n := 0
+	named := make(map[string]bool)
+	lex := lexer{sql: sql}
+	for {
+		tok, ok := lex.next()
+		if !ok || tok.kind == tokSemicolon {
+			return n
+		}
+		if tok.kind != tokParam {
+			continue
+		}
+		switch {
+		case tok.text == "?":
+			n++
+		case tok.text[0] == '?':
+			if i, err := strconv.Atoi(tok.text[1:]); err == nil {
+				n = max(n, i)
+			}
+		case !named[tok.text]:
+			named[tok.text] = true
+			n++
+		}
+	}
+}
+
+// isSignificant reports whether a token is neither whitespace nor a comment.
+func isSignificant(tok token) bool {
+	return tok.kind != tokSpace && tok.kind != tokComment
+}
+
+// skipQuoted skips a quoted string that starts at sql[i] with quote
+// character q. Doubled quote characters are escapes. It returns
+// the position after the closing quote.
+func skipQuoted(sql string, i int, q byte) int {
+	i++
+	for i < len(sql) {
+		if sql[i] == q {
+			if i+1 < len(sql) && sql[i+1] == q {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) || c == '_' || c == '$' || c >= 0x80
+}
@@ -0,0 +1,55 @@
+package sqinn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	tokenString := func(sql string) string {
+		var parts []string
+		l := lexer{sql: sql}
+		for tok, ok := l.next(); ok; tok, ok = l.next() {
+			if tok.kind == tokSpace {
+				continue
+			}
+			kind := [...]string{"space", "comment", "string", "ident", "number", "param", "semicolon", "other"}[tok.kind]
+			parts = append(parts, kind+":"+tok.text)
+		}
+		return strings.Join(parts, " ")
+	}
+	isEq(t, "", tokenString(""))
+	isEq(t, "ident:SELECT other:* ident:FROM ident:users semicolon:;", tokenString("SELECT * FROM users;"))
+	isEq(t, "ident:a other:= param:? ident:AND ident:b other:= param:?12 ident:AND ident:c other:= param::c ident:AND ident:d other:= param:@d ident:AND ident:e other:= param:$e",
+		tokenString("a=? AND b=?12 AND c=:c AND d=@d AND e=$e"))
+	isEq(t, "string:'it''s ?' string:X'00ff' ident:\"a \"\"?\"\" b\" ident:[c ?] ident:`d ?`", tokenString("'it''s ?' X'00ff' \"a \"\"?\"\" b\" [c ?] `d ?`"))
+	isEq(t, "comment:-- a ? comment ident:x comment:/* b ? */ ident:y", tokenString("-- a ? comment\nx /* b ? */ y"))
+	isEq(t, "number:1 other:- number:2 number:1.5e-3 number:.5 number:0xE other:- number:1", tokenString("1-2 1.5e-3 .5 0xE-1"))
+	isEq(t, "ident:a$b other:$ other:: other:: ident:c", tokenString("a$b $ :: c"))
+	// unterminated strings and comments extend to the end
+	isEq(t, "ident:x string:'abc", tokenString("x 'abc"))
+	isEq(t, "ident:x comment:/* abc", tokenString("x /* abc"))
+	isEq(t, "ident:x ident:[abc", tokenString("x [abc"))
+	// positions
+	l := lexer{sql: "a 'b'"}
+	tok, ok := l.next()
+	isEq(t, true, ok)
+	isEq(t, token{tokIdent, 0, "a"}, tok)
+	tok, _ = l.next()
+	isEq(t, token{tokSpace, 1, " "}, tok)
+	tok, _ = l.next()
+	isEq(t, token{tokString, 2, "'b'"}, tok)
+	_, ok = l.next()
+	isEq(t, false, ok)
+}
+
+func TestCountParams(t *testing.T) {
+	isEq(t, 0, countParams("SELECT 1"))
+	isEq(t, 2, countParams("SELECT ?, ?"))
+	isEq(t, 6, countParams("SELECT ?5, ?"))
+	isEq(t, 1, countParams("SELECT ?, ?1"))
+	isEq(t, 3, countParams("SELECT :a, @b, $c, :a"))
+	isEq(t, 3, countParams("SELECT :a, ?, :a, ?3"))
+	isEq(t, 0, countParams("SELECT '?', \"?\", [?], `?` -- ?\n/* ? */"))
+	isEq(t, 1, countParams("SELECT ?; SELECT ?, ?"))
+}
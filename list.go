@@ -0,0 +1,130 @@
+package sqinn
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxListParams is the maximum length of a list value that is expanded
+// into parameters. Longer lists are sent as JSON array.
+const maxListParams = 1000
+
+// expandLists expands list values in params. Each ? placeholder of a
+// list value is replaced with one placeholder per list element. Long lists
+// are replaced with a json_each subquery. If params contains no list values,
+// expandLists returns sql and params unchanged.
+func expandLists(sql string, params []Value) (string, []Value, error) {
+	isList := func(p Value) bool { return p.Type == ValList }
+	if !slices.ContainsFunc(params, isList) {
+		return sql, params, nil
+	}
+	var tokens []token
+	l := lexer{sql: sql}
+	for tok, ok := l.next(); ok; tok, ok = l.next() {
+		tokens = append(tokens, tok)
+	}
+	var sb strings.Builder
+	expanded := make([]Value, 0, len(params))
+	iparam := 0
+	for itok, tok := range tokens {
+		if tok.kind != tokParam {
+			sb.WriteString(tok.text)
+			continue
+		}
+		if tok.text != "?" {
+			return "", nil, fmt.Errorf("list expansion: want ? placeholder but have %s", tok.text)
+		}
+		if iparam >= len(params) {
+			return "", nil, fmt.Errorf("list expansion: want %d params but have more placeholders", len(params))
+		}
+		param := params[iparam]
+		iparam++
+		if param.Type != ValList {
+			sb.WriteString("?")
+			expanded = append(expanded, param)
+			continue
+		}
+		if !isEnclosed(tokens, itok) {
+			return "", nil, fmt.Errorf("list expansion: list param %d must be enclosed in parentheses", iparam-1)
+		}
+		if slices.ContainsFunc(param.List, isList) {
+			return "", nil, fmt.Errorf("list expansion: list param %d contains a list", iparam-1)
+		}
+		if len(param.List) <= maxListParams {
+			for i := range param.List {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString("?")
+			}
+			expanded = append(expanded, param.List...)
+			continue
+		}
+		array, err := jsonArray(param.List)
+		if err != nil {
+			return "", nil, fmt.Errorf("list expansion: list param %d: %w", iparam-1, err)
+		}
+		sb.WriteString("SELECT value FROM json_each(?)")
+		expanded = append(expanded, StringValue(array))
+	}
+	if iparam != len(params) {
+		return "", nil, fmt.Errorf("list expansion: want %d params but have %d placeholders", len(params), iparam)
+	}
+	return sb.String(), expanded, nil
+}
+
+// isEnclosed reports whether tokens[i] is the only token, apart
+// from whitespace and comments, between a pair of parentheses.
+func isEnclosed(tokens []token, i int) bool {
+	prev := i - 1
+	for prev >= 0 && !isSignificant(tokens[prev]) {
+		prev--
+	}
+	next := i + 1
+	for next < len(tokens) && !isSignificant(tokens[next]) {
+		next++
+	}
+	return prev >= 0 && tokens[prev].text == "(" && next < len(tokens) && tokens[next].text == ")"
+}
+
+// jsonArray encodes values as JSON array. Blobs cannot be encoded.
+func jsonArray(values []Value) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, v := range values {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		switch v.Type {
+		case ValNull:
+			sb.WriteString("null")
+		case ValInt32:
+			sb.WriteString(strconv.Itoa(v.Int32))
+		case ValInt64:
+			sb.WriteString(strconv.FormatInt(v.Int64, 10))
+		case ValDouble:
+			if math.IsNaN(v.Double) || math.IsInf(v.Double, 0) {
+				return "", fmt.Errorf("cannot encode double %v as JSON", v.Double)
+			}
+			s := strconv.FormatFloat(v.Double, 'g', -1, 64)
+			if !strings.ContainsAny(s, ".e") {
+				s += ".0" // keep it a REAL value
+			}
+			sb.WriteString(s)
+		case ValString:
+			data, err := json.Marshal(v.String)
+			if err != nil {
+				return "", err
+			}
+			sb.Write(data)
+		default:
+			return "", fmt.Errorf("cannot encode value type %d as JSON", v.Type)
+		}
+	}
+	sb.WriteString("]")
+	return sb.String(), nil
+}
@@ -0,0 +1,76 @@
+package sqinn
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY NOT NULL, name TEXT, score REAL, data BLOB)")
+	var rows [][]Value
+	for i := range 3000 {
+		id := i + 1
+		rows = append(rows, []Value{Int32Value(id), StringValue(fmt.Sprintf("User %d", id)), DoubleValue(float64(id) + 0.5), BlobValue([]byte(fmt.Sprint(id)))})
+	}
+	sq.MustInsertRows("users", []string{"id", "name", "score", "data"}, rows, InsertOptions{})
+	queryIds := func(sql string, params ...Value) []int {
+		t.Helper()
+		var ids []int
+		err := sq.Query(sql, params, []byte{ValInt32}, func(row int, values []Value) {
+			ids = append(ids, values[0].Int32)
+		})
+		isNoErr(t, err)
+		return ids
+	}
+	// small lists
+	isEq(t, "[1 3]", fmt.Sprint(queryIds("SELECT id FROM users WHERE id IN (?) ORDER BY id", List(Int32Value(1), Int32Value(3), Int32Value(5000)))))
+	isEq(t, "[2]", fmt.Sprint(queryIds("SELECT id FROM users WHERE name IN (?) ORDER BY id", List(StringValue("User 2"), StringValue("nobody")))))
+	isEq(t, "[3]", fmt.Sprint(queryIds("SELECT id FROM users WHERE data IN ( /* blobs */ ? )", List(BlobValue([]byte("3"))))))
+	isEq(t, "[]", fmt.Sprint(queryIds("SELECT id FROM users WHERE id IN (?)", List())))
+	// lists mixed with normal params, placeholders in strings and comments are ignored
+	isEq(t, "[2 3]", fmt.Sprint(queryIds("SELECT id FROM users WHERE id > ? AND id IN (?) AND name <> '?' -- ?\n AND id < ? ORDER BY id",
+		Int32Value(1), List(Int32Value(1), Int32Value(2), Int32Value(3), Int32Value(4)), Int32Value(4))))
+	// large lists
+	var ints, int64s, doubles, strs []Value
+	for i := range 2000 {
+		ints = append(ints, Int32Value(1000+i))
+		int64s = append(int64s, Int64Value(int64(1000+i)))
+		doubles = append(doubles, DoubleValue(float64(1000+i)+0.5))
+		strs = append(strs, StringValue(fmt.Sprintf("User %d", 1000+i)))
+	}
+	isEq(t, 2000, len(queryIds("SELECT id FROM users WHERE id IN (?)", List(ints...))))
+	isEq(t, 2000, len(queryIds("SELECT id FROM users WHERE id IN (?)", List(int64s...))))
+	isEq(t, 2000, len(queryIds("SELECT id FROM users WHERE score IN (?)", List(doubles...))))
+	isEq(t, 2000, len(queryIds("SELECT id FROM users WHERE name IN (?)", List(append(strs, NullValue(), StringValue("\"quoted\" \\ ä"))...))))
+	// QueryRows
+	rows, err := sq.QueryRows("SELECT id FROM users WHERE id IN (?) ORDER BY id", []Value{List(Int32Value(7), Int32Value(8))}, []byte{ValInt32})
+	isNoErr(t, err)
+	isEq(t, 2, len(rows))
+	// ExecParams with niterations 1
+	isNoErr(t, sq.ExecParams("DELETE FROM users WHERE id IN (?) OR id = ?", 1, 2, []Value{List(ints...), Int32Value(1)}))
+	isEq(t, 999, len(queryIds("SELECT id FROM users")))
+	// errors
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (?)", []Value{List(make([]Value, 2000)...)}, []byte{ValInt32})
+	isNoErr(t, err)
+	_, err = sq.QueryRows("SELECT id FROM users WHERE data IN (?)", []Value{List(append(ints, BlobValue([]byte{1}))...)}, []byte{ValInt32})
+	isErr(t, err, "list expansion: list param 0: cannot encode value type 5 as JSON")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE score IN (?)", []Value{List(append(ints, DoubleValue(math.NaN()))...)}, []byte{ValInt32})
+	isErr(t, err, "list expansion: list param 0: cannot encode double NaN as JSON")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (?, 1)", []Value{List(Int32Value(1))}, []byte{ValInt32})
+	isErr(t, err, "list expansion: list param 0 must be enclosed in parentheses")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id = ? AND id IN ?", []Value{Int32Value(1), List(Int32Value(1))}, []byte{ValInt32})
+	isErr(t, err, "list expansion: list param 1 must be enclosed in parentheses")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (?)", []Value{List(List())}, []byte{ValInt32})
+	isErr(t, err, "list expansion: list param 0 contains a list")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (:ids)", []Value{List(Int32Value(1))}, []byte{ValInt32})
+	isErr(t, err, "list expansion: want ? placeholder but have :ids")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (?) AND id = ?", []Value{List(Int32Value(1))}, []byte{ValInt32})
+	isErr(t, err, "Query: have 1 params but SQL has 2 parameters")
+	_, err = sq.QueryRows("SELECT id FROM users WHERE id IN (?)", []Value{List(Int32Value(1)), Int32Value(1)}, []byte{ValInt32})
+	isErr(t, err, "Query: have 2 params but SQL has 1 parameters")
+}
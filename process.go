@@ -0,0 +1,77 @@
+package sqinn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StaleAction defines what Launch does with stale sqinn processes, see
+// Options.Stale.
+type StaleAction int
+
+const (
+	// StaleIgnore does not check for stale sqinn processes.
+	StaleIgnore StaleAction = iota
+
+	// StaleFail makes Launch return a *StaleError if there are stale
+	// sqinn processes.
+	StaleFail
+
+	// StaleKill makes Launch kill stale sqinn processes before it
+	// launches sqinn.
+	StaleKill
+)
+
+// A StaleError is returned by Launch if stale sqinn processes hold the
+// database file open, see Options.Stale.
+type StaleError struct {
+	Db   string // The absolute path of the database file.
+	Pids []int  // The process IDs of the stale sqinn processes.
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("sqinn: stale sqinn processes %v hold database %s", e.Pids, e.Db)
+}
+
+// checkStale looks for stale sqinn processes that hold db open. exe is
+// the path of the sqinn executable.
+func checkStale(action StaleAction, exe string, db string, log func(string)) error {
+	if action == StaleIgnore || db == "" || db == ":memory:" || strings.HasPrefix(db, "file:") {
+		return nil
+	}
+	db, err := filepath.Abs(db)
+	if err != nil {
+		return err
+	}
+	db, err = filepath.EvalSymlinks(db)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // nobody holds it
+		}
+		return err
+	}
+	pids, err := staleProcesses(filepath.Base(exe), db)
+	if err != nil || len(pids) == 0 {
+		return err
+	}
+	if action == StaleFail {
+		return &StaleError{db, pids}
+	}
+	for _, pid := range pids {
+		log(fmt.Sprintf("killing stale sqinn process %d", pid))
+		if p, err := os.FindProcess(pid); err == nil {
+			p.Kill()
+		}
+	}
+	// killed processes close their files, wait until they are gone
+	for range 100 {
+		if pids, err = staleProcesses(filepath.Base(exe), db); err != nil || len(pids) == 0 {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return &StaleError{db, pids}
+}
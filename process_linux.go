@@ -0,0 +1,136 @@
+package sqinn
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// sysProcAttr returns the attributes of the sqinn process. Sqinn is killed
+// when the thread that launched it exits, normally when the program exits.
+// Note that the Go runtime exits a thread if its goroutine exits while it
+// is locked with runtime.LockOSThread. Sqinn runs in its own process group,
+// so that terminal signals like SIGINT go to the program only, see
+// Options.ForwardSignals.
+func sysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Pdeathsig: syscall.SIGKILL,
+		Setpgid:   true,
+	}
+}
+
+// staleProcesses returns the IDs of the processes with the command name
+// name, whose parent is a reaper and that have the file db open. These are
+// sqinn processes whose parent has exited without terminating them.
+//
+// Orphans are reparented to the nearest ancestor that is a child
+// subreaper, e.g. systemd --user or tini, or to init. Other ancestors of
+// the program are not reapers, their sqinn children are not stale.
+func staleProcesses(name string, db string) ([]int, error) {
+	if os.Getpid() == 1 {
+		// init, e.g. in a container, is the parent of all orphans
+		return nil, nil
+	}
+	reaper := orphanReaper()
+	// the kernel truncates command names to 15 bytes
+	if len(name) > 15 {
+		name = name[:15]
+	}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue // not a process
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue // gone, or not ours
+		}
+		comm, ppid, ok := parseStat(string(stat))
+		if !ok || comm != name || (ppid != 1 && ppid != reaper) {
+			continue
+		}
+		if holdsFile(dir, db) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// orphanReaper returns the ID of the process that adopts the orphans of
+// the program, or 1 if it cannot be determined. Linux does not tell
+// whether another process is a child subreaper, so orphanReaper makes an
+// orphan and looks at its parent: it starts /bin/sh, which starts sleep in
+// the background and exits. The result is probed once per program.
+var orphanReaper = sync.OnceValue(func() int {
+	out, err := exec.Command("/bin/sh", "-c", "sleep 10 </dev/null >/dev/null 2>&1 & echo $!").Output()
+	if err != nil {
+		return 1
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 1
+	}
+	// the kernel reparents the orphan before sh is reaped
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	syscall.Kill(pid, syscall.SIGKILL)
+	if err != nil {
+		return 1
+	}
+	_, ppid, ok := parseStat(string(stat))
+	if ppid == os.Getpid() {
+		// the program is a subreaper itself, it adopts only its own
+		// orphans, and must reap this one
+		syscall.Wait4(pid, nil, 0, nil)
+		return 1
+	}
+	if !ok || ppid == 0 {
+		return 1 // 0 is a reaper outside of the PID namespace
+	}
+	return ppid
+})
+
+// parseStat parses the command name and the parent process ID of
+// /proc/PID/stat, e.g. "1234 (sqinn) S 1 ...". The command name can
+// contain spaces and parentheses.
+func parseStat(stat string) (string, int, bool) {
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return "", 0, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return stat[start+1 : end], ppid, true
+}
+
+// holdsFile reports whether the process with the /proc directory dir has
+// the file filename open.
+func holdsFile(dir string, filename string) bool {
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return false
+	}
+	for _, fd := range fds {
+		if target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name())); err == nil && target == filename {
+			return true
+		}
+	}
+	return false
+}
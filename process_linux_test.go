@@ -0,0 +1,220 @@
+package sqinn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// alive reports whether a process exists and is not a zombie.
+func alive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return fields[0] != "Z"
+}
+
+// waitGone waits until a process has exited.
+func waitGone(t *testing.T, pid int) {
+	t.Helper()
+	for range 200 {
+		if !alive(pid) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("process %d still alive", pid)
+}
+
+// waitHolds waits until a process has the file filename open.
+func waitHolds(pid int, filename string) {
+	for range 200 {
+		if holdsFile(fmt.Sprintf("/proc/%d", pid), filename) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPdeathsig(t *testing.T) {
+	if os.Getenv("SQINN_TEST_PDEATHSIG") == "1" {
+		// the helper process launches sqinn and waits to be killed
+		sq := MustLaunch(Options{})
+		fmt.Println(sq.cmd.Process.Pid, sq.tempdir)
+		select {}
+	}
+	helper := exec.Command(os.Args[0], "-test.run=^TestPdeathsig$")
+	helper.Env = append(os.Environ(), "SQINN_TEST_PDEATHSIG=1")
+	stdout, err := helper.StdoutPipe()
+	isNoErr(t, err)
+	isNoErr(t, helper.Start())
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	isNoErr(t, err)
+	pidText, tempdir, _ := strings.Cut(strings.TrimSpace(line), " ")
+	t.Cleanup(func() { os.RemoveAll(tempdir) })
+	pid, err := strconv.Atoi(pidText)
+	isNoErr(t, err)
+	// sqinn has its own process group
+	pgid, err := syscall.Getpgid(pid)
+	isNoErr(t, err)
+	isEq(t, pid, pgid)
+	// sqinn dies with its parent
+	isTrue(t, alive(pid), "want sqinn alive")
+	isNoErr(t, helper.Process.Kill())
+	helper.Wait()
+	waitGone(t, pid)
+}
+
+func TestStale(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, "test.db")
+	sq := MustLaunch(Options{Db: db})
+	isNoErr(t, sq.ExecSql("CREATE TABLE t (a)"))
+	isNoErr(t, sq.Close())
+	// a stale sqinn: an orphan named sqinn that holds db open
+	stale := filepath.Join(dir, "sqinn")
+	script := "#!/bin/sh\nexec 3<\"$1\"\nwhile :; do sleep 0.1; done\n"
+	isNoErr(t, os.WriteFile(stale, []byte(script), 0755))
+	out, err := exec.Command("/bin/sh", "-c", stale+" "+db+" >/dev/null 2>&1 & echo $!").Output()
+	isNoErr(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	isNoErr(t, err)
+	t.Cleanup(func() { syscall.Kill(pid, syscall.SIGKILL) })
+	waitHolds(pid, db)
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	isNoErr(t, err)
+	if _, ppid, _ := parseStat(string(stat)); ppid != 1 && ppid != orphanReaper() {
+		t.Skipf("orphans are reparented to %d, not to a reaper", ppid)
+	}
+	// ignore
+	sq = MustLaunch(Options{Db: db})
+	isNoErr(t, sq.Close())
+	isTrue(t, alive(pid), "want stale process alive")
+	// fail
+	_, err = Launch(Options{Db: db, Stale: StaleFail})
+	var staleErr *StaleError
+	isTrue(t, errors.As(err, &staleErr), "want *StaleError but have %v", err)
+	isEq(t, db, staleErr.Db)
+	isEq(t, 1, len(staleErr.Pids))
+	isEq(t, pid, staleErr.Pids[0])
+	isErr(t, err, fmt.Sprintf("sqinn: stale sqinn processes [%d] hold database %s", pid, db))
+	// kill
+	var logs []string
+	sq, err = Launch(Options{Db: db, Stale: StaleKill, Log: func(msg string) { logs = append(logs, msg) }})
+	isNoErr(t, err)
+	isNoErr(t, sq.Close())
+	waitGone(t, pid)
+	isEq(t, fmt.Sprintf("killing stale sqinn process %d", pid), logs[0])
+	// nothing stale anymore
+	sq, err = Launch(Options{Db: db, Stale: StaleFail})
+	isNoErr(t, err)
+	isNoErr(t, sq.Close())
+	// a database that does not exist has no stale processes
+	sq, err = Launch(Options{Db: filepath.Join(dir, "new.db"), Stale: StaleFail})
+	isNoErr(t, err)
+	isNoErr(t, sq.Close())
+}
+
+func TestParseStat(t *testing.T) {
+	comm, ppid, ok := parseStat("1234 (sqinn) S 1 1234 1234 0 -1")
+	isTrue(t, ok, "want ok")
+	isEq(t, "sqinn", comm)
+	isEq(t, 1, ppid)
+	comm, ppid, ok = parseStat("1234 (a) b) (c) S 42 1234")
+	isTrue(t, ok, "want ok")
+	isEq(t, "a) b) (c", comm)
+	isEq(t, 42, ppid)
+	_, _, ok = parseStat("1234 sqinn S 1")
+	isTrue(t, !ok, "want not ok")
+	_, _, ok = parseStat("1234 (sqinn) S")
+	isTrue(t, !ok, "want not ok")
+	_, _, ok = parseStat("1234 (sqinn) S x")
+	isTrue(t, !ok, "want not ok")
+}
+
+// report writes the result of a helper process to the file named by
+// SQINN_TEST_RESULT, because stdout belongs to the test framework.
+func report(t *testing.T, result string) {
+	t.Helper()
+	isNoErr(t, os.WriteFile(os.Getenv("SQINN_TEST_RESULT"), []byte(result), 0o666))
+}
+
+// runHelper runs the test binary as a helper process for test with mode
+// and returns what it reported. The helper is a copy of the test binary
+// named exe, so that it has another command name than the test.
+func runHelper(t *testing.T, test string, exe string, mode string, env ...string) string {
+	t.Helper()
+	binary, err := os.ReadFile(os.Args[0])
+	isNoErr(t, err)
+	isNoErr(t, os.WriteFile(exe, binary, 0755))
+	result := filepath.Join(t.TempDir(), "result")
+	cmd := exec.Command(exe, "-test.run=^"+test+"$")
+	cmd.Env = append(os.Environ(), "SQINN_TEST_STALE="+mode, "SQINN_TEST_RESULT="+result)
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.CombinedOutput()
+	isTrue(t, err == nil, "helper %s: %v\n%s", mode, err, out)
+	data, err := os.ReadFile(result)
+	isNoErr(t, err)
+	return string(data)
+}
+
+func TestStaleSubreaper(t *testing.T) {
+	dir := os.Getenv("SQINN_TEST_DIR")
+	db := filepath.Join(dir, "test.db")
+	switch os.Getenv("SQINN_TEST_STALE") {
+	case "check":
+		// a program below the subreaper checks for stale processes
+		_, err := Launch(Options{Db: db, Stale: StaleFail})
+		report(t, fmt.Sprint(err))
+		return
+	case "reaper":
+		// the subreaper adopts a stale sqinn and runs the check below it
+		const prSetChildSubreaper = 36
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+			t.Fatal(errno)
+		}
+		out, err := exec.Command("/bin/sh", "-c", filepath.Join(dir, "sqinn")+" "+db+" >/dev/null 2>&1 & echo $!").Output()
+		isNoErr(t, err)
+		pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+		isNoErr(t, err)
+		defer syscall.Kill(pid, syscall.SIGKILL)
+		waitHolds(pid, db)
+		check := runHelper(t, "TestStaleSubreaper", filepath.Join(dir, "check"), "check")
+		report(t, fmt.Sprintf("%d %s", pid, check))
+		return
+	case "parent":
+		// an ancestor that is no subreaper has a live sqinn child, which is
+		// not stale
+		cmd := exec.Command(filepath.Join(dir, "sqinn"), db)
+		isNoErr(t, cmd.Start())
+		defer cmd.Process.Kill()
+		waitHolds(cmd.Process.Pid, db)
+		report(t, runHelper(t, "TestStaleSubreaper", filepath.Join(dir, "check"), "check"))
+		return
+	}
+	dir = t.TempDir()
+	db = filepath.Join(dir, "test.db")
+	sq := MustLaunch(Options{Db: db})
+	isNoErr(t, sq.ExecSql("CREATE TABLE t (a)"))
+	isNoErr(t, sq.Close())
+	script := "#!/bin/sh\nexec 3<\"$1\"\nwhile :; do sleep 0.1; done\n"
+	isNoErr(t, os.WriteFile(filepath.Join(dir, "sqinn"), []byte(script), 0755))
+	env := "SQINN_TEST_DIR=" + dir
+	// a stale sqinn adopted by a subreaper is detected
+	result := runHelper(t, "TestStaleSubreaper", filepath.Join(dir, "reaper"), "reaper", env)
+	pid, check, _ := strings.Cut(result, " ")
+	isEq(t, fmt.Sprintf("sqinn: stale sqinn processes [%s] hold database %s", pid, db), check)
+	// the sqinn of an ancestor is not
+	result = runHelper(t, "TestStaleSubreaper", filepath.Join(dir, "parent"), "parent", env)
+	isEq(t, "<nil>", result)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sqinn
+
+import "syscall"
+
+// sysProcAttr returns the attributes of the sqinn process.
+func sysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// staleProcesses is only supported on Linux, elsewhere it finds nothing.
+func staleProcesses(name string, db string) ([]int, error) {
+	return nil, nil
+}
@@ -0,0 +1,170 @@
+//go:build !windows
+
+package sqinn
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeSqinn writes a shell script that is launched instead of sqinn.
+func fakeSqinn(t *testing.T, script string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "sqinn")
+	err := os.WriteFile(filename, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	isNoErr(t, err)
+	return filename
+}
+
+func TestCloseContext(t *testing.T) {
+	ctx := context.Background()
+	t.Run("quit", func(t *testing.T) {
+		sq := MustLaunch(Options{})
+		isNoErr(t, sq.CloseContext(ctx))
+		isTrue(t, sq.cmd.ProcessState.Success(), "want success")
+		_, err := os.Stat(sq.tempdir)
+		isTrue(t, os.IsNotExist(err), "want tempdir removed but have %v", err)
+		// idempotent
+		isNoErr(t, sq.CloseContext(ctx))
+		isNoErr(t, sq.Close())
+		isEq(t, ErrClosed, sq.ExecSql("SELECT 1"))
+	})
+	t.Run("hung", func(t *testing.T) {
+		// reads nothing, answers nothing, exits on SIGTERM
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "exec sleep 60")})
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := sq.CloseContext(ctx)
+		isErr(t, err, "Close: signal: terminated")
+		var exitErr *exec.ExitError
+		isTrue(t, errors.As(err, &exitErr), "want *exec.ExitError but have %T", err)
+		isErr(t, sq.CloseContext(ctx), "Close: signal: terminated")
+	})
+	t.Run("ignore SIGTERM", func(t *testing.T) {
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "trap '' TERM\nexec sleep 60")})
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		isErr(t, sq.CloseContext(ctx), "Close: signal: killed")
+		isTrue(t, time.Since(start) >= defaultKillDelay, "want SIGKILL after %s", defaultKillDelay)
+	})
+	t.Run("kill delay", func(t *testing.T) {
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "trap '' TERM\nexec sleep 60"), KillDelay: 200 * time.Millisecond})
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		isErr(t, sq.CloseContext(ctx), "Close: signal: killed")
+		elapsed := time.Since(start)
+		isTrue(t, elapsed >= 300*time.Millisecond && elapsed < defaultKillDelay, "want SIGKILL after 200ms but have %s", elapsed)
+	})
+	t.Run("pending request", func(t *testing.T) {
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "exec sleep 60")})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var execErr error
+		go func() {
+			defer wg.Done()
+			execErr = sq.ExecSql("SELECT 1")
+		}()
+		// let ExecSql take the lock
+		time.Sleep(50 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		// SIGTERM first, sleep exits on it
+		isErr(t, sq.CloseContext(ctx), "Close: signal: terminated")
+		wg.Wait()
+		isErr(t, execErr, "EOF")
+	})
+	t.Run("pending request ignores SIGTERM", func(t *testing.T) {
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "trap '' TERM\nexec sleep 60"), KillDelay: 200 * time.Millisecond})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var execErr error
+		go func() {
+			defer wg.Done()
+			execErr = sq.ExecSql("SELECT 1")
+		}()
+		// let ExecSql take the lock
+		time.Sleep(50 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		isErr(t, sq.CloseContext(ctx), "Close: signal: killed")
+		wg.Wait()
+		isErr(t, execErr, "EOF")
+	})
+	t.Run("hanging consume func", func(t *testing.T) {
+		sq := MustLaunch(Options{KillDelay: 100 * time.Millisecond})
+		release := make(chan struct{})
+		queryDone := make(chan struct{})
+		go func() {
+			defer close(queryDone)
+			sq.Query("SELECT 1", nil, []byte{ValInt32}, func(row int, values []Value) {
+				<-release
+			})
+		}()
+		// let Query take the lock
+		time.Sleep(50 * time.Millisecond)
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := sq.CloseContext(ctx)
+		isErr(t, err, "Close: context deadline exceeded")
+		isTrue(t, errors.Is(err, context.DeadlineExceeded), "want context.DeadlineExceeded")
+		isTrue(t, time.Since(start) < 5*time.Second, "want bounded wait")
+		// the instance is not closed, a later Close finishes the job
+		close(release)
+		<-queryDone
+		isErr(t, sq.Close(), "Close: signal: terminated")
+		isEq(t, ErrClosed, sq.ExecSql("SELECT 1"))
+	})
+	t.Run("exit status and stderr", func(t *testing.T) {
+		var mu sync.Mutex
+		var lines []string
+		log := func(msg string) {
+			mu.Lock()
+			defer mu.Unlock()
+			lines = append(lines, msg)
+		}
+		// reads the quit request, logs and exits without answering
+		sq := MustLaunch(Options{Sqinn: fakeSqinn(t, "head -c 1 >/dev/null\necho bye >&2\nexit 3"), Log: log})
+		err := sq.CloseContext(ctx)
+		isErr(t, err, "Close: exit status 3")
+		var exitErr *exec.ExitError
+		isTrue(t, errors.As(err, &exitErr), "want *exec.ExitError but have %T", err)
+		isEq(t, 3, exitErr.ExitCode())
+		mu.Lock()
+		defer mu.Unlock()
+		isEq(t, 1, len(lines))
+		isEq(t, "[sqinn] bye", lines[0])
+	})
+}
+
+func TestForwardSignals(t *testing.T) {
+	lines := make(chan string, 10)
+	// logs SIGUSR1 and exits on SIGTERM
+	script := "trap 'echo usr1 >&2' USR1\nwhile :; do sleep 0.05; done"
+	sq := MustLaunch(Options{
+		Sqinn:          fakeSqinn(t, script),
+		Log:            func(msg string) { lines <- msg },
+		ForwardSignals: []os.Signal{syscall.SIGUSR1},
+	})
+	// let the shell install its trap
+	time.Sleep(100 * time.Millisecond)
+	isNoErr(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	select {
+	case line := <-lines:
+		isEq(t, "[sqinn] usr1", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("want forwarded signal")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	isErr(t, sq.CloseContext(ctx), "Close: signal: terminated")
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package sqinn
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminate asks a process to exit.
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,9 @@
+package sqinn
+
+import "os"
+
+// terminate asks a process to exit. Windows has no SIGTERM, so it kills
+// the process.
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,342 @@
+/*
+Package queries loads named SQL queries from .sql files.
+
+A query file holds one or more queries. Each query starts with a
+"-- name:" line, optionally followed by a "-- coltypes:" line that lists
+the types of the result columns, and a "-- params:" line that lists the
+names and Go types of the parameters:
+
+	-- name: GetUserByID :one
+	-- coltypes: int64, string?
+	-- params: id int64
+	SELECT id, name FROM users WHERE id = ?;
+
+	-- name: DeleteUser :exec
+	DELETE FROM users WHERE id = ?;
+
+Valid coltypes are int32, int64, double, string and blob. A "?" suffix marks
+a column as nullable. A query without coltypes returns no rows. The query
+name may be followed by a kind, one of :one, :many and :exec, which tells
+code generators like sqinn-gen how many rows the query returns. The query
+text extends up to the next "-- name:" line or the end of the file. A
+trailing semicolon is removed.
+
+Query files are usually embedded with go:embed and loaded at startup:
+
+	//go:embed sql/*.sql
+	var sqlFiles embed.FS
+
+	set, err := queries.Load(sq, sqlFiles, "sql/*.sql")
+	...
+	rows, err := set.MustGet("GetUserByID").QueryRows(sq, sqinn.Int64Value(42))
+*/
+package queries
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/cvilsmeier/sqinn-go/v2"
+)
+
+// A Query is a named SQL query.
+type Query struct {
+	Name     string  // The query name, from the "-- name:" line.
+	Kind     string  // The query kind "one", "many" or "exec", from the "-- name:" line, or empty.
+	Sql      string  // The SQL text.
+	Coltypes []byte  // The result column types, from the "-- coltypes:" line, or nil.
+	Nullable []bool  // Nullable[i] is true if result column i is nullable, or nil.
+	Params   []Param // The parameters, from the "-- params:" line, or nil.
+	File     string  // The file the query was loaded from.
+	Line     int     // The line of the "-- name:" line in File, starting at 1.
+}
+
+// A Param is a query parameter.
+type Param struct {
+	Name string // The parameter name, e.g. "id".
+	Type string // The Go type, e.g. "int64".
+}
+
+// Query executes the query and calls consume for each result row.
+func (q *Query) Query(sq *sqinn.Sqinn, consume sqinn.ConsumeFunc, params ...sqinn.Value) error {
+	if len(q.Coltypes) == 0 {
+		return fmt.Errorf("query %s: no coltypes", q.Name)
+	}
+	return sq.Query(q.Sql, params, q.Coltypes, consume)
+}
+
+// QueryRows executes the query and returns all result rows.
+func (q *Query) QueryRows(sq *sqinn.Sqinn, params ...sqinn.Value) ([][]sqinn.Value, error) {
+	if len(q.Coltypes) == 0 {
+		return nil, fmt.Errorf("query %s: no coltypes", q.Name)
+	}
+	return sq.QueryRows(q.Sql, params, q.Coltypes)
+}
+
+// Exec executes the query once with the given params.
+func (q *Query) Exec(sq *sqinn.Sqinn, params ...sqinn.Value) error {
+	return sq.ExecParams(q.Sql, 1, len(params), params)
+}
+
+// A Set is a set of queries, accessible by name.
+type Set struct {
+	queries []*Query
+	byName  map[string]*Query
+}
+
+// Parse parses all files in fsys that match one of the glob patterns, see
+// fs.Glob. If no pattern is given, Parse uses "*.sql". Files are parsed in
+// lexical order. Query names must be unique across all files.
+func Parse(fsys fs.FS, patterns ...string) (*Set, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.sql"}
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !slices.Contains(files, m) {
+				files = append(files, m)
+			}
+		}
+	}
+	slices.Sort(files)
+	set := &Set{byName: make(map[string]*Query)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		queries, err := parseFile(file, string(data))
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range queries {
+			if prev := set.byName[q.Name]; prev != nil {
+				return nil, fmt.Errorf("%s:%d: duplicate query name %s, first defined at %s:%d", q.File, q.Line, q.Name, prev.File, prev.Line)
+			}
+			set.byName[q.Name] = q
+			set.queries = append(set.queries, q)
+		}
+	}
+	return set, nil
+}
+
+// Load is like Parse, but also validates the queries against sq, see Validate.
+func Load(sq *sqinn.Sqinn, fsys fs.FS, patterns ...string) (*Set, error) {
+	set, err := Parse(fsys, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if err := set.Validate(sq); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Validate describes each query with Sqinn.Describe, so that syntax errors
+// and unknown tables or columns are detected early. It also checks that
+// queries with coltypes return exactly len(coltypes) columns, and that
+// queries with params have exactly len(params) parameters. It returns an
+// error for the first invalid query.
+func (s *Set) Validate(sq *sqinn.Sqinn) error {
+	for _, q := range s.queries {
+		if err := validate(sq, q); err != nil {
+			return fmt.Errorf("%s:%d: query %s: %w", q.File, q.Line, q.Name, err)
+		}
+	}
+	return nil
+}
+
+func validate(sq *sqinn.Sqinn, q *Query) error {
+	desc, err := sq.Describe(q.Sql)
+	if err != nil {
+		return err
+	}
+	if desc.Columns != nil && len(q.Coltypes) > 0 && len(desc.Columns) != len(q.Coltypes) {
+		return fmt.Errorf("have %d coltypes but query returns %d columns", len(q.Coltypes), len(desc.Columns))
+	}
+	if desc.Columns == nil && len(q.Coltypes) > 0 {
+		return fmt.Errorf("have %d coltypes but query returns no rows", len(q.Coltypes))
+	}
+	if q.Params != nil && len(q.Params) != desc.NumParams {
+		return fmt.Errorf("have %d params but query has %d", len(q.Params), desc.NumParams)
+	}
+	return nil
+}
+
+// Get returns the query with the given name, or nil and false if there is none.
+func (s *Set) Get(name string) (*Query, bool) {
+	q, ok := s.byName[name]
+	return q, ok
+}
+
+// MustGet is like Get but panics if there is no query with the given name.
+func (s *Set) MustGet(name string) *Query {
+	q, ok := s.byName[name]
+	if !ok {
+		panic(fmt.Sprintf("query %s not found", name))
+	}
+	return q
+}
+
+// Queries returns all queries, in the order they were parsed.
+func (s *Set) Queries() []*Query {
+	return slices.Clone(s.queries)
+}
+
+// parseFile parses the queries of a file.
+func parseFile(file, text string) ([]*Query, error) {
+	var queries []*Query
+	var q *Query
+	var sql []string
+	flush := func() error {
+		if q == nil {
+			return nil
+		}
+		q.Sql = strings.TrimSpace(strings.Join(sql, "\n"))
+		q.Sql = strings.TrimSpace(strings.TrimSuffix(q.Sql, ";"))
+		if q.Sql == "" {
+			return fmt.Errorf("%s:%d: query %s: no SQL", file, q.Line, q.Name)
+		}
+		queries = append(queries, q)
+		return nil
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, 1<<20)
+	lineno := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineno++
+		key, value, isAnnotation := parseAnnotation(line)
+		switch {
+		case isAnnotation && key == "name":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			name, kind, err := parseName(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, lineno, err)
+			}
+			q = &Query{Name: name, Kind: kind, File: file, Line: lineno}
+			sql = nil
+		case isAnnotation && key == "coltypes":
+			if q == nil {
+				return nil, fmt.Errorf("%s:%d: coltypes without name", file, lineno)
+			}
+			coltypes, nullable, err := parseColtypes(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, lineno, err)
+			}
+			q.Coltypes = coltypes
+			q.Nullable = nullable
+		case isAnnotation && key == "params":
+			if q == nil {
+				return nil, fmt.Errorf("%s:%d: params without name", file, lineno)
+			}
+			params, err := parseParams(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, lineno, err)
+			}
+			q.Params = params
+		case q == nil:
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("%s:%d: SQL without name", file, lineno)
+			}
+		default:
+			sql = append(sql, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// parseAnnotation parses a "-- key: value" line.
+func parseAnnotation(line string) (key, value string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !ok {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false
+	}
+	key = strings.TrimSpace(key)
+	switch key {
+	case "name", "coltypes", "params":
+		return key, strings.TrimSpace(value), true
+	}
+	return "", "", false
+}
+
+// parseName parses the value of a "-- name:" line, a query name and an
+// optional kind.
+func parseName(s string) (name, kind string, err error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 || strings.HasPrefix(fields[0], ":") {
+		return "", "", fmt.Errorf("invalid query name %q", s)
+	}
+	if len(fields) == 2 {
+		switch fields[1] {
+		case ":one", ":many", ":exec":
+			kind = fields[1][1:]
+		default:
+			return "", "", fmt.Errorf("invalid query kind %q", fields[1])
+		}
+	}
+	return fields[0], kind, nil
+}
+
+// parseColtypes parses a comma-separated list of coltypes. A "?" suffix
+// marks a nullable column.
+func parseColtypes(s string) ([]byte, []bool, error) {
+	var coltypes []byte
+	var nullable []bool
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		base, null := strings.CutSuffix(name, "?")
+		switch strings.ToLower(base) {
+		case "int32":
+			coltypes = append(coltypes, sqinn.ValInt32)
+		case "int64":
+			coltypes = append(coltypes, sqinn.ValInt64)
+		case "double":
+			coltypes = append(coltypes, sqinn.ValDouble)
+		case "string":
+			coltypes = append(coltypes, sqinn.ValString)
+		case "blob":
+			coltypes = append(coltypes, sqinn.ValBlob)
+		default:
+			return nil, nil, fmt.Errorf("invalid coltype %q", name)
+		}
+		nullable = append(nullable, null)
+	}
+	return coltypes, nullable, nil
+}
+
+// parseParams parses a comma-separated list of "name type" pairs.
+func parseParams(s string) ([]Param, error) {
+	params := []Param{}
+	if s == "" {
+		return params, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		fields := strings.Fields(p)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid param %q", strings.TrimSpace(p))
+		}
+		params = append(params, Param{fields[0], fields[1]})
+	}
+	return params, nil
+}
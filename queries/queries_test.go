@@ -0,0 +1,178 @@
+package queries
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cvilsmeier/sqinn-go/v2"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"sql/users.sql": {Data: []byte(`-- Queries for the users table.
+
+-- name: GetUserByID :one
+-- coltypes: int64, string?
+-- params: id int64
+-- Comments in the query text are kept.
+SELECT id, name
+FROM users
+WHERE id = ?;
+
+-- name: ListUsers
+-- coltypes: INT64,string
+SELECT id, name FROM users ORDER BY id
+
+-- name: InsertUser
+INSERT INTO users (id, name) VALUES (?, ?);
+`)},
+		"sql/items.sql": {Data: []byte(`-- name: CountItems
+-- coltypes: int32
+SELECT COUNT(*) FROM items;
+`)},
+		"other.txt": {Data: []byte(`not sql`)},
+	}
+}
+
+func TestParse(t *testing.T) {
+	set, err := Parse(testFS(), "sql/*.sql")
+	isNoErr(t, err)
+	queries := set.Queries()
+	isEq(t, 4, len(queries))
+	// files are parsed in lexical order
+	isEq(t, "CountItems", queries[0].Name)
+	isEq(t, "sql/items.sql", queries[0].File)
+	isEq(t, 1, queries[0].Line)
+	q, ok := set.Get("GetUserByID")
+	isTrue(t, ok, "want GetUserByID")
+	isEq(t, "sql/users.sql", q.File)
+	isEq(t, 3, q.Line)
+	isEq(t, "one", q.Kind)
+	isEq(t, "-- Comments in the query text are kept.\nSELECT id, name\nFROM users\nWHERE id = ?", q.Sql)
+	isEq(t, 2, len(q.Coltypes))
+	isEq(t, sqinn.ValInt64, q.Coltypes[0])
+	isEq(t, sqinn.ValString, q.Coltypes[1])
+	isEq(t, 2, len(q.Nullable))
+	isTrue(t, !q.Nullable[0] && q.Nullable[1], "want nullable [false true] but have %v", q.Nullable)
+	isEq(t, 1, len(q.Params))
+	isEq(t, Param{"id", "int64"}, q.Params[0])
+	isEq(t, "", set.MustGet("ListUsers").Kind)
+	isTrue(t, set.MustGet("ListUsers").Params == nil, "want nil params")
+	isEq(t, "SELECT id, name FROM users ORDER BY id", set.MustGet("ListUsers").Sql)
+	isEq(t, 0, len(set.MustGet("InsertUser").Coltypes))
+	_, ok = set.Get("NoSuchQuery")
+	isTrue(t, !ok, "want no NoSuchQuery")
+	isPanic(t, "query NoSuchQuery not found", func() { set.MustGet("NoSuchQuery") })
+	// default pattern
+	set, err = Parse(fstest.MapFS{"a.sql": {Data: []byte("-- name: A\nSELECT 1")}})
+	isNoErr(t, err)
+	isEq(t, 1, len(set.Queries()))
+	// errors
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"SELECT 1", "x.sql:1: SQL without name"},
+		{"-- coltypes: int32\nSELECT 1", "x.sql:1: coltypes without name"},
+		{"-- name: A\n-- coltypes: int32, float\nSELECT 1", `x.sql:2: invalid coltype "float"`},
+		{"-- name: A\n\n-- name: B\nSELECT 1", "x.sql:1: query A: no SQL"},
+		{"-- name: A B C\nSELECT 1", `x.sql:1: invalid query name "A B C"`},
+		{"-- name: :one\nSELECT 1", `x.sql:1: invalid query name ":one"`},
+		{"-- name: A :all\nSELECT 1", `x.sql:1: invalid query kind ":all"`},
+		{"-- params: id int64\nSELECT 1", "x.sql:1: params without name"},
+		{"-- name: A\n-- params: id int64, name\nSELECT 1", `x.sql:2: invalid param "name"`},
+		{"-- name: A\nSELECT 1\n-- name: A\nSELECT 2", "x.sql:3: duplicate query name A, first defined at x.sql:1"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(fstest.MapFS{"x.sql": {Data: []byte(tt.text)}})
+		isErr(t, err, tt.want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	sq := sqinn.MustLaunch(sqinn.Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	// tables do not exist yet
+	_, err := Load(sq, testFS(), "sql/*.sql")
+	isErr(t, err, "sql/items.sql:1: query CountItems: sqinn: no such table: items")
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	sq.MustExecSql("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	set, err := Load(sq, testFS(), "sql/*.sql")
+	isNoErr(t, err)
+	isNoErr(t, set.MustGet("InsertUser").Exec(sq, sqinn.Int64Value(1), sqinn.StringValue("Alice")))
+	isNoErr(t, set.MustGet("InsertUser").Exec(sq, sqinn.Int64Value(2), sqinn.StringValue("Bob")))
+	rows, err := set.MustGet("GetUserByID").QueryRows(sq, sqinn.Int64Value(2))
+	isNoErr(t, err)
+	isEq(t, 1, len(rows))
+	isEq(t, int64(2), rows[0][0].Int64)
+	isEq(t, "Bob", rows[0][1].String)
+	var names []string
+	err = set.MustGet("ListUsers").Query(sq, func(row int, values []sqinn.Value) {
+		names = append(names, values[1].String)
+	})
+	isNoErr(t, err)
+	isEq(t, 2, len(names))
+	isEq(t, "Alice", names[0])
+	_, err = set.MustGet("InsertUser").QueryRows(sq)
+	isErr(t, err, "query InsertUser: no coltypes")
+	// coltypes must match the result columns
+	_, err = Load(sq, fstest.MapFS{"x.sql": {Data: []byte("-- name: A\n-- coltypes: int32\nSELECT id, name FROM users")}})
+	isErr(t, err, "x.sql:1: query A: have 1 coltypes but query returns 2 columns")
+	_, err = Load(sq, fstest.MapFS{"x.sql": {Data: []byte("-- name: A\n-- coltypes: int32\nDELETE FROM users")}})
+	isErr(t, err, "x.sql:1: query A: have 1 coltypes but query returns no rows")
+	// params must match the query parameters
+	_, err = Load(sq, fstest.MapFS{"x.sql": {Data: []byte("-- name: A\n-- params: id int64, name string\nDELETE FROM users WHERE id = ?")}})
+	isErr(t, err, "x.sql:1: query A: have 2 params but query has 1")
+	_, err = Load(sq, fstest.MapFS{"x.sql": {Data: []byte("-- name: A\n-- params:\nDELETE FROM users WHERE id = ?")}})
+	isErr(t, err, "x.sql:1: query A: have 0 params but query has 1")
+	_, err = Load(sq, fstest.MapFS{"x.sql": {Data: []byte("-- name: A\nSELEKT 1")}})
+	isErr(t, err, `x.sql:1: query A: sqinn: near "SELEKT": syntax error`)
+}
+
+// assertion library
+
+func isTrue(t *testing.T, condition bool, format string, args ...any) {
+	t.Helper()
+	if !condition {
+		t.Fatalf(format, args...)
+	}
+}
+
+func isNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("want no err but have %s", err)
+	}
+}
+
+func isErr(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("want err but have nil")
+	} else if err.Error() != want {
+		t.Fatalf("want err %q but have %q", want, err.Error())
+	}
+}
+
+func isEq[T comparable](t *testing.T, want, have T) {
+	t.Helper()
+	if want != have {
+		t.Fatalf("want %T(%v) but have %T(%v)", want, want, have, have)
+	}
+}
+
+func isPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("want panic but did not panic")
+			return
+		}
+		isEq(t, want, r.(string))
+	}()
+	f()
+	t.Fatalf("must not come here, want f() to panic")
+}
@@ -0,0 +1,129 @@
+package sqinn
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// QueryJSON executes a query and writes the result rows to w as a JSON array
+// of objects, e.g. [{"id":1,"name":"Alice"},{"id":2,"name":null}]. Names
+// holds the object keys, one for each coltype. Values are encoded like in
+// WriteRowsJSON.
+//
+// Rows are streamed from sqinn to w, one row at a time, without collecting
+// them in memory. If writing to w fails, QueryJSON still reads all remaining
+// rows and returns the write error afterwards. If the query fails, the
+// output written so far is incomplete.
+func (sq *Sqinn) QueryJSON(w io.Writer, sql string, params []Value, coltypes []byte, names []string) error {
+	return sq.queryJSON(context.Background(), w, sql, params, coltypes, names, false)
+}
+
+// MustQueryJSON is the same as QueryJSON except it panics on error.
+func (sq *Sqinn) MustQueryJSON(w io.Writer, sql string, params []Value, coltypes []byte, names []string) {
+	must(0, sq.QueryJSON(w, sql, params, coltypes, names))
+}
+
+// QueryNDJSON is like QueryJSON but writes newline-delimited JSON, one
+// JSON object per row and line.
+func (sq *Sqinn) QueryNDJSON(w io.Writer, sql string, params []Value, coltypes []byte, names []string) error {
+	return sq.queryJSON(context.Background(), w, sql, params, coltypes, names, true)
+}
+
+// MustQueryNDJSON is the same as QueryNDJSON except it panics on error.
+func (sq *Sqinn) MustQueryNDJSON(w io.Writer, sql string, params []Value, coltypes []byte, names []string) {
+	must(0, sq.QueryNDJSON(w, sql, params, coltypes, names))
+}
+
+func (sq *Sqinn) queryJSON(ctx context.Context, w io.Writer, sql string, params []Value, coltypes []byte, names []string, ndjson bool) error {
+	method, format := "QueryJSON", ExportJSON
+	if ndjson {
+		method, format = "QueryNDJSON", ExportNDJSON
+	}
+	if len(names) != len(coltypes) {
+		return sq.argError(method, "want %d names but have %d", len(coltypes), len(names))
+	}
+	return sq.Export(ctx, w, format, sql, params, ExportOptions{Coltypes: coltypes, Names: names})
+}
+
+// A JSONHandler is a http.Handler that serves the result rows of a query
+// as JSON, see QueryJSON. If the Accept header of a request contains
+// "application/x-ndjson", rows are served as NDJSON, see QueryNDJSON.
+// If the request context is canceled, e.g. because the client has gone
+// away, the handler stops writing rows, see Export.
+type JSONHandler struct {
+	// Sqinn executes the query. Required.
+	Sqinn *Sqinn
+
+	// Sql is the query. Required.
+	Sql string
+
+	// Coltypes are the column types of the query. Required.
+	Coltypes []byte
+
+	// Names are the object keys, one for each coltype. Required.
+	Names []string
+
+	// Params builds the query params from a request. If it returns an error,
+	// the handler responds with 400 Bad Request and the error message.
+	// Default is nil (no params).
+	Params func(r *http.Request) ([]Value, error)
+
+	// ErrorLog logs query and write errors.
+	// Default is nil (use the log package's standard logger).
+	ErrorLog *log.Logger
+}
+
+func (h *JSONHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params []Value
+	if h.Params != nil {
+		var err error
+		params, err = h.Params(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	ndjson := strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	cw := &countingWriter{w: w}
+	err := h.Sqinn.queryJSON(r.Context(), cw, h.Sql, params, h.Coltypes, h.Names, ndjson)
+	if err == nil {
+		return
+	}
+	h.logf("sqinn.JSONHandler: %s %s: %s", r.Method, r.URL.Path, err)
+	if cw.n == 0 {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	// The status line has been sent, we cannot report the error anymore.
+	// Abort the response so that the client sees an incomplete response
+	// instead of a truncated, seemingly successful one.
+	panic(http.ErrAbortHandler)
+}
+
+func (h *JSONHandler) logf(format string, args ...any) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,140 @@
+package sqinn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testJSONSqinn(t *testing.T) *Sqinn {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, score REAL, data BLOB)")
+	sq.MustExecParams("INSERT INTO users (id, name, score, data) VALUES (?, ?, ?, ?)", 3, 4, []Value{
+		Int32Value(1), StringValue("Alice"), DoubleValue(1.5), BlobValue([]byte{1, 2}),
+		Int32Value(2), StringValue("<Bob>"), NullValue(), NullValue(),
+		Int32Value(3), NullValue(), DoubleValue(-2), BlobValue([]byte{0xFF}),
+	})
+	return sq
+}
+
+type failingWriter struct {
+	n int // fail after n bytes
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestQueryJSON(t *testing.T) {
+	sq := testJSONSqinn(t)
+	coltypes := []byte{ValInt32, ValString, ValDouble, ValBlob}
+	names := []string{"id", "name", "score", `da"ta`}
+	var buf bytes.Buffer
+	isNoErr(t, sq.QueryJSON(&buf, "SELECT id, name, score, data FROM users ORDER BY id", nil, coltypes, names))
+	want := `[{"id":1,"name":"Alice","score":1.5,"da\"ta":"AQI="},` +
+		`{"id":2,"name":"<Bob>","score":null,"da\"ta":null},` +
+		`{"id":3,"name":null,"score":-2,"da\"ta":"/w=="}]`
+	isEq(t, want, buf.String())
+	var objs []map[string]any
+	isNoErr(t, json.Unmarshal(buf.Bytes(), &objs))
+	isEq(t, 3, len(objs))
+	// NDJSON
+	buf.Reset()
+	isNoErr(t, sq.QueryNDJSON(&buf, "SELECT id, name FROM users WHERE id >= ? ORDER BY id", []Value{Int32Value(2)}, []byte{ValInt32, ValString}, []string{"id", "name"}))
+	isEq(t, "{\"id\":2,\"name\":\"<Bob>\"}\n{\"id\":3,\"name\":null}\n", buf.String())
+	// no rows
+	buf.Reset()
+	isNoErr(t, sq.QueryJSON(&buf, "SELECT id FROM users WHERE id < 0", nil, []byte{ValInt32}, []string{"id"}))
+	isEq(t, "[]", buf.String())
+	buf.Reset()
+	isNoErr(t, sq.QueryNDJSON(&buf, "SELECT id FROM users WHERE id < 0", nil, []byte{ValInt32}, []string{"id"}))
+	isEq(t, "", buf.String())
+	// errors
+	isPanic(t, "want 1 names but have 2", func() {
+		sq.QueryJSON(&buf, "SELECT id FROM users", nil, []byte{ValInt32}, []string{"id", "name"})
+	})
+	err := sq.QueryJSON(&buf, "SELECT id FROM no_such_table", nil, []byte{ValInt32}, []string{"id"})
+	isErr(t, err, "sqinn: no such table: no_such_table")
+	// a write error does not break the protocol
+	sq.MustExecSql("WITH RECURSIVE n(i) AS (SELECT 4 UNION ALL SELECT i+1 FROM n WHERE i < 2000) INSERT INTO users (id, name) SELECT i, 'user' || i FROM n")
+	err = sq.QueryJSON(&failingWriter{n: 5000}, "SELECT id, name FROM users", nil, []byte{ValInt32, ValString}, []string{"id", "name"})
+	isErr(t, err, "disk full")
+	rows := sq.MustQueryRows("SELECT COUNT(*) FROM users", nil, []byte{ValInt32})
+	isEq(t, 2000, rows[0][0].Int32)
+}
+
+func TestJSONHandler(t *testing.T) {
+	sq := testJSONSqinn(t)
+	var logBuf bytes.Buffer
+	handler := &JSONHandler{
+		Sqinn:    sq,
+		Sql:      "SELECT id, name FROM users WHERE id >= ? ORDER BY id",
+		Coltypes: []byte{ValInt32, ValString},
+		Names:    []string{"id", "name"},
+		Params: func(r *http.Request) ([]Value, error) {
+			minID := r.URL.Query().Get("min")
+			if minID == "" {
+				return nil, errors.New("missing min")
+			}
+			return []Value{StringValue(minID)}, nil
+		},
+		ErrorLog: log.New(&logBuf, "", 0),
+	}
+	serve := func(url, accept string) *http.Response {
+		r := httptest.NewRequest("GET", url, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+	body := func(resp *http.Response) string {
+		data, err := io.ReadAll(resp.Body)
+		isNoErr(t, err)
+		return string(data)
+	}
+	resp := serve("/users?min=2", "")
+	isEq(t, http.StatusOK, resp.StatusCode)
+	isEq(t, "application/json", resp.Header.Get("Content-Type"))
+	isEq(t, `[{"id":2,"name":"<Bob>"},{"id":3,"name":null}]`, body(resp))
+	resp = serve("/users?min=3", "application/x-ndjson")
+	isEq(t, http.StatusOK, resp.StatusCode)
+	isEq(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	isEq(t, "{\"id\":3,\"name\":null}\n", body(resp))
+	resp = serve("/users", "")
+	isEq(t, http.StatusBadRequest, resp.StatusCode)
+	isEq(t, "missing min\n", body(resp))
+	// query errors
+	handler.Sql = "SELECT id, name FROM no_such_table WHERE id >= ?"
+	resp = serve("/users?min=1", "")
+	isEq(t, http.StatusInternalServerError, resp.StatusCode)
+	isEq(t, "Internal Server Error\n", body(resp))
+	isTrue(t, strings.Contains(logBuf.String(), "sqinn.JSONHandler: GET /users: sqinn: no such table: no_such_table"), "log %q", logBuf.String())
+	// canceled requests
+	handler.Sql = "SELECT id, name FROM users WHERE id >= ? ORDER BY id"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequestWithContext(ctx, "GET", "/users?min=1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	resp = w.Result()
+	isEq(t, http.StatusInternalServerError, resp.StatusCode)
+	isTrue(t, strings.Contains(logBuf.String(), "sqinn.JSONHandler: GET /users: context canceled"), "log %q", logBuf.String())
+}
@@ -0,0 +1,67 @@
+package sqinn
+
+import "fmt"
+
+// ReturningFunc is a callback function that is called by ExecReturning once
+// for each row returned by a RETURNING clause.
+// Iteration is the iteration index that produced the row, starting at 0.
+// Row is the row index within that iteration, starting at 0.
+// Values contains the row values for this row.
+// Within a ReturningFunc, no calls to sqinn.Exec/Query are allowed.
+type ReturningFunc func(iteration, row int, values []Value)
+
+// ExecReturning executes a SQL statement that has a RETURNING clause,
+// possibly multiple times, and fetches the returned rows.
+//
+// The niterations, nparams and produce arguments work like in Exec.
+// Parameter values produced by produce can be NULL.
+//
+// Coltypes defines the types of the RETURNING columns to be fetched.
+//
+// Consume is called exactly once for each returned row, together
+// with the iteration index that produced the row.
+//
+// Each iteration is a separate sqinn request. ExecReturning holds the sqinn
+// instance for all iterations, so no other calls are interleaved. If an
+// iteration fails, ExecReturning stops and returns the error; earlier
+// iterations are not rolled back.
+func (sq *Sqinn) ExecReturning(sql string, niterations, nparams int, produce ProduceFunc, coltypes []byte, consume ReturningFunc) error {
+	if niterations < 0 {
+		return sq.argError("ExecReturning", "invalid niterations < 0")
+	}
+	if nparams < 0 {
+		return sq.argError("ExecReturning", "invalid nparams < 0")
+	}
+	if nparams > 0 && produce == nil {
+		return sq.argError("ExecReturning", "invalid nparams > 0 && produce == nil")
+	}
+	if err := sq.checkQuery("ExecReturning", coltypes, consume != nil); err != nil {
+		return err
+	}
+	if niterations == 0 {
+		return nil
+	}
+	if n := countParams(sql); n != nparams {
+		return &ArgError{"ExecReturning", fmt.Sprintf("nparams is %d but SQL has %d parameters", nparams, n)}
+	}
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	params := make([]Value, nparams)
+	for iteration := range niterations {
+		if nparams > 0 {
+			produce(iteration, params)
+		}
+		err := sq.query("ExecReturning", sql, params, coltypes, func(row int, values []Value) {
+			consume(iteration, row, values)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustExecReturning is the same as ExecReturning except it panics on error.
+func (sq *Sqinn) MustExecReturning(sql string, niterations, nparams int, produce ProduceFunc, coltypes []byte, consume ReturningFunc) {
+	must(0, sq.ExecReturning(sql, niterations, nparams, produce, coltypes, consume))
+}
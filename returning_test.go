@@ -0,0 +1,76 @@
+package sqinn
+
+import (
+	"testing"
+)
+
+func TestExecReturning(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE users (id INTEGER PRIMARY KEY NOT NULL, name TEXT, created TEXT DEFAULT 'now')")
+	type returned struct {
+		iteration int
+		row       int
+		id        int64
+		name      string
+		isNull    bool
+		created   string
+	}
+	var rows []returned
+	names := []Value{StringValue("Alice"), NullValue(), StringValue("Carol")}
+	err := sq.ExecReturning("INSERT INTO users (name) VALUES (?) RETURNING id, name, created", 3, 1, func(iteration int, params []Value) {
+		params[0] = names[iteration]
+	}, []byte{ValInt64, ValString, ValString}, func(iteration, row int, values []Value) {
+		isEq(t, 3, len(values))
+		rows = append(rows, returned{iteration, row, values[0].Int64, values[1].String, values[1].Type == ValNull, values[2].String})
+	})
+	isNoErr(t, err)
+	isEq(t, 3, len(rows))
+	isEq(t, returned{0, 0, 1, "Alice", false, "now"}, rows[0])
+	isEq(t, returned{1, 0, 2, "", true, "now"}, rows[1])
+	isEq(t, returned{2, 0, 3, "Carol", false, "now"}, rows[2])
+	// multiple rows per iteration, and iterations without rows
+	rows = nil
+	sq.MustExecReturning("UPDATE users SET name = 'X' WHERE id > ? RETURNING id", 3, 1, func(iteration int, params []Value) {
+		params[0] = Int32Value(iteration)
+	}, []byte{ValInt64}, func(iteration, row int, values []Value) {
+		rows = append(rows, returned{iteration: iteration, row: row, id: values[0].Int64})
+	})
+	isEq(t, 6, len(rows))
+	isEq(t, returned{iteration: 0, row: 0, id: 1}, rows[0])
+	isEq(t, returned{iteration: 0, row: 1, id: 2}, rows[1])
+	isEq(t, returned{iteration: 0, row: 2, id: 3}, rows[2])
+	isEq(t, returned{iteration: 1, row: 0, id: 2}, rows[3])
+	isEq(t, returned{iteration: 1, row: 1, id: 3}, rows[4])
+	isEq(t, returned{iteration: 2, row: 0, id: 3}, rows[5])
+	// niterations 0 is a NO-OP
+	isNoErr(t, sq.ExecReturning("DELETE FROM users RETURNING id", 0, 0, nil, []byte{ValInt64}, func(iteration, row int, values []Value) {
+		t.Fatal("must not be called")
+	}))
+	// errors stop the batch
+	err = sq.ExecReturning("INSERT INTO users (id) VALUES (?) RETURNING id", 2, 1, func(iteration int, params []Value) {
+		params[0] = Int32Value(3)
+	}, []byte{ValInt64}, func(iteration, row int, values []Value) {})
+	isErr(t, err, "sqinn: UNIQUE constraint failed: users.id")
+	// invalid arguments
+	isPanic(t, "invalid niterations < 0", func() {
+		sq.ExecReturning("DELETE FROM users RETURNING id", -1, 0, nil, []byte{ValInt64}, func(iteration, row int, values []Value) {})
+	})
+	isPanic(t, "invalid nparams < 0", func() {
+		sq.ExecReturning("DELETE FROM users RETURNING id", 1, -1, nil, []byte{ValInt64}, func(iteration, row int, values []Value) {})
+	})
+	isPanic(t, "invalid nparams > 0 && produce == nil", func() {
+		sq.ExecReturning("DELETE FROM users WHERE id=? RETURNING id", 1, 1, nil, []byte{ValInt64}, func(iteration, row int, values []Value) {})
+	})
+	isPanic(t, "no coltypes", func() {
+		sq.ExecReturning("DELETE FROM users RETURNING id", 1, 0, nil, nil, func(iteration, row int, values []Value) {})
+	})
+	isPanic(t, "no consume func", func() {
+		sq.ExecReturning("DELETE FROM users RETURNING id", 1, 0, nil, []byte{ValInt64}, nil)
+	})
+	isPanic(t, "coltype ValNull not allowed in ExecReturning", func() {
+		sq.ExecReturning("DELETE FROM users RETURNING id", 1, 0, nil, []byte{ValNull}, func(iteration, row int, values []Value) {})
+	})
+}
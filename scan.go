@@ -0,0 +1,201 @@
+package sqinn
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// A ScanError describes a value that could not be scanned.
+type ScanError struct {
+	Column int    // The column index, starting at 0.
+	Msg    string // What went wrong.
+	Err    error  // The underlying error, e.g. of a codec or a sql.Scanner, or nil.
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// ScanInto scans values into dests. The number of dests must be equal to the
+// number of values. See Scanner.Scan for supported dest types.
+// Time values are scanned in TimeText format, see Sqinn.ScanInto for the
+// time format of Options.TimeFormat.
+func ScanInto(values []Value, dests ...any) error {
+	if len(dests) != len(values) {
+		return fmt.Errorf("want %d dests but have %d", len(values), len(dests))
+	}
+	return Scan(values).Scan(dests...)
+}
+
+// Scan scans the next len(dests) values into dests. Each dest must be
+// a pointer. Supported dest types are
+//
+//	*Value  -> any value, including NULL
+//	*int, *int64  -> ValInt32 or ValInt64
+//	*float64  -> ValDouble
+//	*string  -> ValString
+//	*[]byte  -> ValBlob
+//	*bool  -> ValInt32 or ValInt64, true if not 0
+//	*time.Time  -> see ValueTime, in TimeText format or, if the Scanner
+//	  was created with Sqinn.Scan, in the format of Options.TimeFormat
+//	sql.Scanner  -> any value, e.g. *sql.NullString
+//	**T  -> NULL sets *dest to nil, other values are scanned into a new T
+//
+// and pointers to types for which a codec is registered, if the Scanner was
+// created with Sqinn.Scan. NULL values can only be scanned into *Value,
+// sql.Scanner, **T and codec types.
+//
+// Scan returns a *ScanError with the column index if a value cannot be
+// scanned. It stops at the first error.
+func (s *Scanner) Scan(dests ...any) error {
+	for _, dest := range dests {
+		v := s.Next()
+		if s.err != nil {
+			return s.err
+		}
+		if err := s.scan(v, dest); err != nil {
+			if s.err == nil {
+				s.err = &ScanError{s.i, err.Error(), err}
+			}
+			return s.err
+		}
+	}
+	return nil
+}
+
+func (s *Scanner) scan(v Value, dest any) error {
+	if s.codecs != nil {
+		if ok, err := s.codecs.Decode(v, dest); ok || err != nil {
+			return err
+		}
+	}
+	switch d := dest.(type) {
+	case *Value:
+		*d = v
+		return nil
+	case sql.Scanner:
+		return d.Scan(driverValue(v))
+	}
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("want non-nil pointer but have %T", dest)
+	}
+	if rv.Elem().Kind() == reflect.Pointer {
+		if v.Type == ValNull {
+			rv.Elem().SetZero()
+			return nil
+		}
+		p := reflect.New(rv.Elem().Type().Elem())
+		if err := s.scan(v, p.Interface()); err != nil {
+			return err
+		}
+		rv.Elem().Set(p)
+		return nil
+	}
+	if v.Type == ValNull {
+		return fmt.Errorf("unexpected NULL for %T", dest)
+	}
+	switch d := dest.(type) {
+	case *int:
+		i, err := valueInt64(v)
+		if err != nil {
+			return err
+		}
+		if i < math.MinInt || i > math.MaxInt {
+			return fmt.Errorf("value %d overflows int", i)
+		}
+		*d = int(i)
+	case *int64:
+		i, err := valueInt64(v)
+		if err != nil {
+			return err
+		}
+		*d = i
+	case *bool:
+		i, err := valueInt64(v)
+		if err != nil {
+			return err
+		}
+		*d = i != 0
+	case *float64:
+		if v.Type != ValDouble {
+			return fmt.Errorf("want ValDouble but have %s", valTypeName(v.Type))
+		}
+		*d = v.Double
+	case *string:
+		if v.Type != ValString {
+			return fmt.Errorf("want ValString but have %s", valTypeName(v.Type))
+		}
+		*d = v.String
+	case *[]byte:
+		if v.Type != ValBlob {
+			return fmt.Errorf("want ValBlob but have %s", valTypeName(v.Type))
+		}
+		*d = v.Blob
+	case *time.Time:
+		t, err := ValueTime(v, s.timefmt)
+		if err != nil {
+			return err
+		}
+		*d = t
+	default:
+		return fmt.Errorf("unsupported dest type %T", dest)
+	}
+	return nil
+}
+
+// valueInt64 returns the integer of a ValInt32 or ValInt64 value.
+func valueInt64(v Value) (int64, error) {
+	switch v.Type {
+	case ValInt32:
+		return int64(v.Int32), nil
+	case ValInt64:
+		return v.Int64, nil
+	}
+	return 0, fmt.Errorf("want ValInt32 or ValInt64 but have %s", valTypeName(v.Type))
+}
+
+// driverValue converts a Value to a value that can be passed to sql.Scanner.
+func driverValue(v Value) any {
+	switch v.Type {
+	case ValInt32:
+		return int64(v.Int32)
+	case ValInt64:
+		return v.Int64
+	case ValDouble:
+		return v.Double
+	case ValString:
+		return v.String
+	case ValBlob:
+		return v.Blob
+	}
+	return nil
+}
+
+// valTypeName returns the name of a value type, e.g. "ValInt32".
+func valTypeName(vt byte) string {
+	switch vt {
+	case ValNull:
+		return "ValNull"
+	case ValInt32:
+		return "ValInt32"
+	case ValInt64:
+		return "ValInt64"
+	case ValDouble:
+		return "ValDouble"
+	case ValString:
+		return "ValString"
+	case ValBlob:
+		return "ValBlob"
+	case ValList:
+		return "ValList"
+	}
+	return fmt.Sprintf("value type %d", vt)
+}
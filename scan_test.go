@@ -0,0 +1,156 @@
+package sqinn
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestScanInto(t *testing.T) {
+	values := []Value{
+		Int32Value(1),
+		Int64Value(2),
+		DoubleValue(3.5),
+		StringValue("4"),
+		BlobValue([]byte{5}),
+		Int32Value(1),
+		StringValue("2025-08-15T13:14:15Z"),
+		NullValue(),
+		StringValue("x"),
+		NullValue(),
+		Int32Value(11),
+	}
+	var (
+		i    int
+		i64  int64
+		f    float64
+		s    string
+		b    []byte
+		ok   bool
+		tm   time.Time
+		v    Value
+		ns   sql.NullString
+		pi   *int
+		pi11 *int
+	)
+	isNoErr(t, ScanInto(values, &i, &i64, &f, &s, &b, &ok, &tm, &v, &ns, &pi, &pi11))
+	isEq(t, 1, i)
+	isEq(t, 2, i64)
+	isEq(t, 3.5, f)
+	isEq(t, "4", s)
+	isEq(t, "\x05", string(b))
+	isEq(t, true, ok)
+	isEq(t, time.Date(2025, 8, 15, 13, 14, 15, 0, time.UTC), tm)
+	isEq(t, ValNull, v.Type)
+	isEq(t, sql.NullString{String: "x", Valid: true}, ns)
+	isTrue(t, pi == nil, "want nil but have %v", pi)
+	isEq(t, 11, *pi11)
+	// errors
+	var scanErr *ScanError
+	err := ScanInto(values, &i)
+	isErr(t, err, "want 11 dests but have 1")
+	err = ScanInto([]Value{Int32Value(1), NullValue()}, &i, &s)
+	isErr(t, err, "column 1: unexpected NULL for *string")
+	isTrue(t, errors.As(err, &scanErr), "want *ScanError")
+	isEq(t, 1, scanErr.Column)
+	err = ScanInto([]Value{Int32Value(1), StringValue("x")}, &i, &i)
+	isErr(t, err, "column 1: want ValInt32 or ValInt64 but have ValString")
+	err = ScanInto([]Value{Int32Value(1)}, &f)
+	isErr(t, err, "column 0: want ValDouble but have ValInt32")
+	err = ScanInto([]Value{Int32Value(1)}, &s)
+	isErr(t, err, "column 0: want ValString but have ValInt32")
+	err = ScanInto([]Value{StringValue("x")}, &b)
+	isErr(t, err, "column 0: want ValBlob but have ValString")
+	err = ScanInto([]Value{StringValue("x")}, &tm)
+	isErr(t, err, `column 0: cannot parse time "x"`)
+	err = ScanInto([]Value{Int32Value(1)}, i)
+	isErr(t, err, "column 0: want non-nil pointer but have int")
+	err = ScanInto([]Value{Int32Value(1)}, &[]int{})
+	isErr(t, err, "column 0: unsupported dest type *[]int")
+	errScan := errors.New("scan failed")
+	err = ScanInto([]Value{Int32Value(1)}, testScanner{errScan})
+	isErr(t, err, "column 0: scan failed")
+	isTrue(t, errors.Is(err, errScan), "want errScan")
+	err = ScanInto([]Value{StringValue("x")}, &pi)
+	isErr(t, err, "column 0: want ValInt32 or ValInt64 but have ValString")
+	// scanner
+	sc := Scan(values)
+	isNoErr(t, sc.Scan(&i, &i64))
+	isNoErr(t, sc.Scan())
+	isEq(t, 3.5, sc.Double())
+	isErr(t, sc.Scan(&i), "column 3: want ValInt32 or ValInt64 but have ValString")
+	isErr(t, sc.Err(), "column 3: want ValInt32 or ValInt64 but have ValString")
+	// scanner with codecs
+	sq := &Sqinn{codecs: testCodecs()}
+	var addr netip.Addr
+	isNoErr(t, sq.Scan([]Value{StringValue("::1"), NullValue()}).Scan(&addr, &pi))
+	isEq(t, netip.MustParseAddr("::1"), addr)
+	err = sq.Scan([]Value{StringValue("no addr")}).Scan(&addr)
+	_, parseErr := netip.ParseAddr("no addr")
+	isEq(t, parseErr.Error(), errors.Unwrap(err).Error())
+}
+
+func TestScannerErr(t *testing.T) {
+	// overrun
+	sc := Scan([]Value{Int32Value(1)})
+	isEq(t, 1, sc.Int32())
+	isNoErr(t, sc.Err())
+	isEq(t, ValNull, sc.Next().Type)
+	isErr(t, sc.Err(), "column 1: no more values, have only 1")
+	isEq(t, 0, sc.Int32())
+	isErr(t, sc.Err(), "column 1: no more values, have only 1")
+	// unexpected NULL
+	sc = Scan([]Value{NullValue(), NullValue(), NullValue(), NullValue(), NullValue()})
+	_, ok := sc.NextInt32()
+	isEq(t, false, ok)
+	isNoErr(t, sc.Err())
+	isEq(t, 0, sc.Int32())
+	isErr(t, sc.Err(), "column 1: unexpected NULL")
+	// type mismatch
+	for _, tc := range []struct {
+		f    func(sc *Scanner)
+		want string
+	}{
+		{func(sc *Scanner) { sc.Int32() }, "column 0: want ValInt32 but have ValString"},
+		{func(sc *Scanner) { sc.NextInt32() }, "column 0: want ValInt32 but have ValString"},
+		{func(sc *Scanner) { sc.Int64() }, "column 0: want ValInt64 but have ValString"},
+		{func(sc *Scanner) { sc.NextInt64() }, "column 0: want ValInt64 but have ValString"},
+		{func(sc *Scanner) { sc.Double() }, "column 0: want ValDouble but have ValString"},
+		{func(sc *Scanner) { sc.NextDouble() }, "column 0: want ValDouble but have ValString"},
+		{func(sc *Scanner) { sc.Blob() }, "column 0: want ValBlob but have ValString"},
+		{func(sc *Scanner) { sc.NextBlob() }, "column 0: want ValBlob but have ValString"},
+	} {
+		sc = Scan([]Value{StringValue("1")})
+		tc.f(sc)
+		isErr(t, sc.Err(), tc.want)
+	}
+	// a mismatch is not NULL
+	sc = Scan([]Value{Int32Value(1)})
+	s, ok := sc.NextString()
+	isEq(t, "", s)
+	isEq(t, true, ok)
+	isErr(t, sc.Err(), "column 0: want ValString but have ValInt32")
+	// integers are converted if they fit
+	sc = Scan([]Value{Int64Value(-7), Int32Value(8), Int64Value(math.MaxInt32 + 1)})
+	i32, ok := sc.NextInt32()
+	isEq(t, -7, i32)
+	isEq(t, true, ok)
+	isEq(t, int64(8), sc.Int64())
+	isNoErr(t, sc.Err())
+	i32, ok = sc.NextInt32()
+	isEq(t, 0, i32)
+	isEq(t, true, ok)
+	isErr(t, sc.Err(), "column 2: value 2147483648 overflows ValInt32")
+}
+
+// testScanner is a sql.Scanner that fails with err.
+type testScanner struct {
+	err error
+}
+
+func (s testScanner) Scan(src any) error {
+	return s.err
+}
@@ -0,0 +1,337 @@
+package sqinn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// nextStatement finds the first complete SQL statement in sql. A statement
+// is complete if it is terminated by a semicolon outside of strings, quoted
+// identifiers and comments. Inside CREATE TRIGGER, only the semicolon of
+// "; END;" terminates the statement, like in sqlite3_complete, so that
+// CASE ... END inside the trigger body does not.
+//
+// It returns the statement text, including the semicolon, and the number
+// of bytes consumed. It returns ok=false if sql holds no complete statement.
+func nextStatement(sql string) (stmt string, n int, ok bool) {
+	sp := newSplitter(sql)
+	stmt, ok = sp.next()
+	return stmt, len(stmt), ok
+}
+
+// A splitter splits SQL text into statements like nextStatement. The text
+// can be written piece by piece, the splitter does not lex the text again
+// that it has lexed before.
+type splitter struct {
+	buf          strings.Builder // the text, buf.String()[start:] is not returned yet
+	start        int
+	lex          lexer    // lex.pos is after the last token that cannot continue
+	words        []string // leading keywords of the statement, upper case
+	afterSemi    bool     // the previous significant token is ;
+	endAfterSemi bool     // the previous significant token is END after ;
+}
+
+// newSplitter returns a splitter for the whole text sql. The text is not
+// copied, so write must not be called.
+func newSplitter(sql string) *splitter {
+	return &splitter{lex: lexer{sql: sql}}
+}
+
+// write appends text.
+func (sp *splitter) write(text string) {
+	if sp.start > 0 {
+		// drop the returned statements, a string that a Builder has
+		// returned is never modified, so only the rest is copied
+		rest := sp.buf.String()[sp.start:]
+		sp.buf.Reset()
+		sp.buf.WriteString(rest)
+		sp.lex.pos -= sp.start
+		sp.lex.resume = max(0, sp.lex.resume-sp.start)
+		sp.start = 0
+	}
+	sp.buf.WriteString(text)
+	sp.lex.sql = sp.buf.String()
+}
+
+// next returns the next complete statement, including the semicolon, or
+// false if the text written so far holds no complete statement.
+func (sp *splitter) next() (string, bool) {
+	sql := sp.lex.sql
+	for {
+		tok, ok := sp.lex.next()
+		if !ok {
+			return "", false
+		}
+		if tok.kind != tokSemicolon && sp.lex.pos == len(sql) {
+			// the token can continue in the next piece of text, lex it
+			// again then, but a long string or comment only from here on
+			sp.lex.pos = tok.pos
+			if c := sql[len(sql)-1]; c != '\'' && c != '"' && c != '`' && c != '*' {
+				sp.lex.resume = len(sql)
+			}
+			return "", false
+		}
+		if !isSignificant(tok) {
+			continue
+		}
+		if tok.kind == tokSemicolon {
+			if !isTrigger(sp.words) || sp.endAfterSemi {
+				stmt := sql[sp.start:sp.lex.pos]
+				sp.start = sp.lex.pos
+				sp.words = sp.words[:0]
+				sp.afterSemi, sp.endAfterSemi = false, false
+				return stmt, true
+			}
+			sp.afterSemi, sp.endAfterSemi = true, false
+			continue
+		}
+		word := strings.ToUpper(tok.text)
+		sp.endAfterSemi = sp.afterSemi && word == "END"
+		sp.afterSemi = false
+		if len(sp.words) < 3 {
+			sp.words = append(sp.words, word)
+		}
+	}
+}
+
+// rest returns the text that is not returned yet.
+func (sp *splitter) rest() string {
+	return sp.lex.sql[sp.start:]
+}
+
+// isTrigger reports whether the leading keywords of a statement are
+// CREATE [TEMP|TEMPORARY] TRIGGER.
+func isTrigger(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		return len(words) >= 3 && words[2] == "TRIGGER"
+	}
+	return words[1] == "TRIGGER"
+}
+
+// isBlank reports whether sql holds only whitespace, comments and semicolons.
+func isBlank(sql string) bool {
+	lex := lexer{sql: sql}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return true
+		}
+		if isSignificant(tok) && tok.kind != tokSemicolon {
+			return false
+		}
+	}
+}
+
+// firstSignificant returns the byte offset of the first significant
+// token in sql, or len(sql) if there is none.
+func firstSignificant(sql string) int {
+	lex := lexer{sql: sql}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return len(sql)
+		}
+		if isSignificant(tok) {
+			return tok.pos
+		}
+	}
+}
+
+// firstKeywords returns the first two significant tokens of a statement,
+// in upper case.
+func firstKeywords(sql string) (string, string) {
+	lex := lexer{sql: sql}
+	var words []string
+	for len(words) < 2 {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if isSignificant(tok) {
+			words = append(words, strings.ToUpper(tok.text))
+		}
+	}
+	words = append(words, "", "")
+	return words[0], words[1]
+}
+
+// ScriptOptions control how ExecScript executes a script.
+type ScriptOptions struct {
+	// Transaction executes the whole script inside one SAVEPOINT, so that
+	// either all statements take effect or none. The script itself must
+	// then not contain BEGIN, COMMIT or ROLLBACK statements.
+	// Default is false (each statement takes effect on its own).
+	Transaction bool
+}
+
+// A ScriptError describes a failed statement of a script.
+type ScriptError struct {
+	Statement int    // The statement number, starting at 1.
+	Line      int    // The line of the statement in the script, starting at 1.
+	Column    int    // The column (byte offset) of the statement in its line, starting at 1.
+	Sql       string // The statement text.
+	Err       error  // The error reported by sqinn.
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement %d (line %d, column %d): %s", e.Statement, e.Line, e.Column, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ExecScript executes a SQL script statement by statement. Statements
+// are separated by semicolons outside of string literals, quoted identifiers
+// and comments. A CREATE TRIGGER statement extends to the semicolon after
+// its END. Empty statements are skipped.
+//
+// If a statement fails, ExecScript stops and returns a *ScriptError. If
+// opt.Transaction is true, all statements are rolled back. Otherwise the
+// statements before the failing one keep their effect, but if the script has
+// started a transaction that is still open, ExecScript rolls it back.
+func (sq *Sqinn) ExecScript(script string, opt ScriptOptions) error {
+	if opt.Transaction {
+		if err := sq.ExecSql("SAVEPOINT sqinn_script"); err != nil {
+			return err
+		}
+	}
+	r := &scriptRunner{sq: sq, line: 1, col: 1}
+	sp := newSplitter(script)
+	for {
+		stmt, ok := sp.next()
+		if !ok {
+			break
+		}
+		if err := r.exec(stmt); err != nil {
+			return sq.endScript(opt, err)
+		}
+	}
+	// execute an unterminated last statement
+	return sq.endScript(opt, r.exec(sp.rest()))
+}
+
+// MustExecScript is the same as ExecScript except it panics on error.
+func (sq *Sqinn) MustExecScript(script string, opt ScriptOptions) {
+	must(0, sq.ExecScript(script, opt))
+}
+
+// endScript ends the savepoint of ExecScript, if any.
+func (sq *Sqinn) endScript(opt ScriptOptions, err error) error {
+	if !opt.Transaction {
+		return err
+	}
+	if err != nil {
+		sq.ExecSql("ROLLBACK TO sqinn_script")
+	}
+	if releaseErr := sq.ExecSql("RELEASE sqinn_script"); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// LoadScript reads a SQL script from r and executes it statement by
+// statement, e.g. a script written by Dump. Statements are split like in
+// ExecScript. The script is streamed, LoadScript holds only the current
+// statement in memory, and it lexes the script only once.
+//
+// If a statement fails, LoadScript stops and returns a *ScriptError. If
+// the script has started a transaction that is still open, LoadScript
+// rolls it back.
+func (sq *Sqinn) LoadScript(r io.Reader) error {
+	br := bufio.NewReader(r)
+	var sp splitter
+	runner := &scriptRunner{sq: sq, line: 1, col: 1}
+	for {
+		text, readErr := br.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			runner.rollback()
+			return readErr
+		}
+		sp.write(text)
+		for {
+			stmt, ok := sp.next()
+			if !ok {
+				break
+			}
+			if err := runner.exec(stmt); err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			break // EOF
+		}
+	}
+	// execute an unterminated last statement
+	return runner.exec(sp.rest())
+}
+
+// MustLoadScript is the same as LoadScript except it panics on error.
+func (sq *Sqinn) MustLoadScript(r io.Reader) {
+	must(0, sq.LoadScript(r))
+}
+
+// A scriptRunner executes the statements of a script, one after another,
+// and keeps track of their position in the script.
+type scriptRunner struct {
+	sq    *Sqinn
+	nstmt int  // number of executed statements
+	line  int  // line of the next statement text
+	col   int  // column of the next statement text
+	inTx  bool // the script has started a transaction
+}
+
+// exec executes a statement text, which may have leading whitespace and
+// comments, and may be blank.
+func (r *scriptRunner) exec(stmt string) error {
+	start := firstSignificant(stmt)
+	r.advance(stmt[:start])
+	line, col := r.line, r.col
+	r.advance(stmt[start:])
+	if isBlank(stmt) {
+		return nil
+	}
+	r.nstmt++
+	sql := stmt[start:]
+	if err := r.sq.ExecSql(sql); err != nil {
+		r.rollback()
+		return &ScriptError{r.nstmt, line, col, sql, err}
+	}
+	switch first, second := firstKeywords(sql); first {
+	case "BEGIN":
+		r.inTx = true
+	case "COMMIT", "END":
+		r.inTx = false
+	case "ROLLBACK":
+		if second != "TO" {
+			r.inTx = false
+		}
+	}
+	return nil
+}
+
+// advance advances the position by text.
+func (r *scriptRunner) advance(text string) {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			r.line++
+			r.col = 1
+		} else {
+			r.col++
+		}
+	}
+}
+
+// rollback rolls back a transaction that was started by the script.
+func (r *scriptRunner) rollback() {
+	if r.inTx {
+		r.sq.ExecSql("ROLLBACK")
+		r.inTx = false
+	}
+}
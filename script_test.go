@@ -0,0 +1,153 @@
+package sqinn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecScript(t *testing.T) {
+	sq := testDumpSqinn(t)
+	script := "CREATE TABLE t (a TEXT);\n" +
+		"CREATE TRIGGER t_upper AFTER INSERT ON t BEGIN\n" +
+		"  UPDATE t SET a = upper(a) WHERE rowid = new.rowid;\n" +
+		"END;\n" +
+		"INSERT INTO t VALUES ('x;y'); -- trailing; comment\n" +
+		"INSERT INTO t VALUES ('z')"
+	isNoErr(t, sq.ExecScript(script, ScriptOptions{}))
+	rows := sq.MustQueryRows("SELECT a FROM t ORDER BY rowid", nil, []byte{ValString})
+	isEq(t, 2, len(rows))
+	isEq(t, "X;Y", rows[0][0].String)
+	isEq(t, "Z", rows[1][0].String)
+	// error without transaction: statements before the failing one keep their effect
+	script = "INSERT INTO t VALUES ('a');\n" +
+		"/* comment */  INSERT INTO t VALUES ('b'); INSERT INTO no_such_table VALUES (1);\n" +
+		"INSERT INTO t VALUES ('c');\n"
+	err := sq.ExecScript(script, ScriptOptions{})
+	isErr(t, err, "statement 3 (line 2, column 44): sqinn: no such table: no_such_table")
+	var scriptErr *ScriptError
+	isTrue(t, errors.As(err, &scriptErr), "want *ScriptError but have %T", err)
+	isEq(t, "INSERT INTO no_such_table VALUES (1);", scriptErr.Sql)
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isEq(t, 4, rows[0][0].Int32)
+	// error with transaction: all statements are rolled back
+	err = sq.ExecScript(script, ScriptOptions{Transaction: true})
+	isErr(t, err, "statement 3 (line 2, column 44): sqinn: no such table: no_such_table")
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isEq(t, 4, rows[0][0].Int32)
+	isNoErr(t, sq.ExecScript("DELETE FROM t; INSERT INTO t VALUES ('only')", ScriptOptions{Transaction: true}))
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isEq(t, 1, rows[0][0].Int32)
+	// a transaction started by the script is rolled back on error
+	err = sq.ExecScript("BEGIN; DELETE FROM t; SELECT * FROM no_such_table; COMMIT;", ScriptOptions{})
+	isErr(t, err, "statement 3 (line 1, column 23): sqinn: no such table: no_such_table")
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isEq(t, 1, rows[0][0].Int32)
+	// many statements
+	script = strings.Repeat("INSERT INTO t VALUES ('many');\n", 20000)
+	isNoErr(t, sq.ExecScript(script, ScriptOptions{Transaction: true}))
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t WHERE a = 'MANY'", nil, []byte{ValInt32})
+	isEq(t, 20000, rows[0][0].Int32)
+	// blank scripts
+	isNoErr(t, sq.ExecScript("", ScriptOptions{}))
+	isNoErr(t, sq.ExecScript(" ;; -- nothing\n", ScriptOptions{Transaction: true}))
+}
+
+func TestSplitter(t *testing.T) {
+	scripts := []string{
+		"SELECT 1; SELECT 2;",
+		"SELECT 'a;''b;\nc''' AS x; SELECT X'00ff', \"q;\"\"\", `b;`, [c;];",
+		"/* a; *** b; */ SELECT 1 -- c;\n; SELECT 2;",
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN new.a > 0 THEN 1 ELSE 2 END; END; SELECT 3; tail",
+		"SELECT '\n\n\n;\n'; SELECT /*\n;\n*/ 4;",
+	}
+	for _, script := range scripts {
+		// the statements of the whole script
+		var want []string
+		rest := script
+		for {
+			stmt, n, ok := nextStatement(rest)
+			if !ok {
+				break
+			}
+			want = append(want, stmt)
+			rest = rest[n:]
+		}
+		// written in pieces of any size
+		for size := 1; size <= len(script); size++ {
+			var sp splitter
+			var have []string
+			for i := 0; i < len(script); i += size {
+				sp.write(script[i:min(i+size, len(script))])
+				for {
+					stmt, ok := sp.next()
+					if !ok {
+						break
+					}
+					have = append(have, stmt)
+				}
+			}
+			isEq(t, strings.Join(want, "|"), strings.Join(have, "|"))
+			isEq(t, rest, sp.rest())
+		}
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	sq := testDumpSqinn(t)
+	script := "-- a comment\n" +
+		"CREATE TABLE t (a TEXT);\n" +
+		"INSERT INTO t VALUES ('a;b'); INSERT INTO t VALUES (\"c\");\n" +
+		";;\n" +
+		"/* multi\nline; */ INSERT INTO t VALUES ('d')"
+	isNoErr(t, sq.LoadScript(strings.NewReader(script)))
+	rows := sq.MustQueryRows("SELECT a FROM t ORDER BY rowid", nil, []byte{ValString})
+	isEq(t, 3, len(rows))
+	isEq(t, "a;b", rows[0][0].String)
+	isEq(t, "d", rows[2][0].String)
+	// error with line number, open transaction is rolled back
+	script = "BEGIN;\n" +
+		"INSERT INTO t VALUES ('e');\n" +
+		"\n" +
+		"-- comment\n" +
+		"INSERT INTO\n" +
+		"  no_such_table VALUES (1);\n" +
+		"COMMIT;\n"
+	err := sq.LoadScript(strings.NewReader(script))
+	isErr(t, err, "statement 3 (line 5, column 1): sqinn: no such table: no_such_table")
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM t", nil, []byte{ValInt32})
+	isEq(t, 3, rows[0][0].Int32)
+	sq.MustExecSql("BEGIN") // no transaction is open
+	sq.MustExecSql("COMMIT")
+	// a string with many lines
+	text := strings.Repeat("line;\n", 10000)
+	isNoErr(t, sq.LoadScript(strings.NewReader("DELETE FROM t;\nINSERT INTO t VALUES ('"+text+"');\n")))
+	rows = sq.MustQueryRows("SELECT a FROM t", nil, []byte{ValString})
+	isEq(t, 1, len(rows))
+	isEq(t, text, rows[0][0].String)
+}
+
+func TestNextStatement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		stmt string
+		ok   bool
+	}{
+		{"SELECT 1; SELECT 2;", "SELECT 1;", true},
+		{"SELECT ';'", "", false},
+		{"SELECT 1 -- ;\n", "", false},
+		{"SELECT [a;b], \"c;d\", `e;f`;", "SELECT [a;b], \"c;d\", `e;f`;", true},
+		{"CREATE TRIGGER x AFTER INSERT ON t BEGIN SELECT 1; SELECT 2;", "", false},
+		{"CREATE TEMP TRIGGER x AFTER INSERT ON t BEGIN SELECT 1; END; SELECT 3;", "CREATE TEMP TRIGGER x AFTER INSERT ON t BEGIN SELECT 1; END;", true},
+		{"create trigger x after insert on t begin select 1; end ;", "create trigger x after insert on t begin select 1; end ;", true},
+		{"CREATE TABLE trigger (end TEXT);", "CREATE TABLE trigger (end TEXT);", true},
+		{"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN new.a > 0 THEN 1 ELSE 2 END; END; SELECT 1;", "CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN new.a > 0 THEN 1 ELSE 2 END; END;", true},
+		{"CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT CASE 1 WHEN 1 THEN 2 END;", "", false},
+	}
+	for _, tt := range tests {
+		stmt, n, ok := nextStatement(tt.sql)
+		isEq(t, tt.ok, ok)
+		isEq(t, tt.stmt, stmt)
+		isEq(t, len(tt.stmt), n)
+	}
+}
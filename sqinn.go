@@ -8,11 +8,14 @@ package sqinn
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"sync"
 	"time"
@@ -47,6 +50,75 @@ type Options struct {
 	// Log can be nil, then nothing will be logged
 	// Default is nil (no logging).
 	Log func(msg string)
+
+	// TimeFormat is the format for time.Time values in Sqinn.Bind,
+	// Sqinn.BindE, Sqinn.ScanInto and in Scanners created with Sqinn.Scan.
+	// Default is TimeText.
+	TimeFormat TimeFormat
+
+	// Codecs holds codecs for custom Go types. They are used by
+	// Sqinn.Bind, Sqinn.BindE, Sqinn.ScanInto and by Scanners created
+	// with Sqinn.Scan.
+	// Default is nil (no codecs).
+	Codecs *Codecs
+
+	// NoPanic makes methods return an *ArgError instead of panicking if they
+	// are called with invalid arguments, e.g. a negative niterations or
+	// empty coltypes. The Must methods still panic on any error.
+	// Default is false (panic on invalid arguments).
+	NoPanic bool
+
+	// MaxFrameSize is the maximum size in bytes of a response frame from
+	// sqinn. A frame holds one or more result rows, sqinn starts a new frame
+	// after about 1 MB, so a frame must fit the largest result row plus 1 MB.
+	// Larger frames are a protocol error, see ErrProtocol. The limit keeps
+	// a broken or hostile sqinn from making the program allocate up to 2 GB
+	// per frame. Programs that query rows larger than about 63 MB, e.g.
+	// with large blobs, must raise it, up to 2147483647, the largest frame
+	// size of the protocol.
+	// Default is 67108864 (64 MB).
+	MaxFrameSize int
+
+	// MaxStringSize is the maximum size in bytes of a string value
+	// returned by sqinn. Larger strings are a protocol error.
+	// Default is 1000000000, the default maximum length of SQLite.
+	MaxStringSize int
+
+	// MaxBlobSize is the maximum size in bytes of a blob value returned
+	// by sqinn. Larger blobs are a protocol error.
+	// Default is 1000000000, the default maximum length of SQLite.
+	MaxBlobSize int
+
+	// ForwardSignals are signals that are forwarded to the sqinn process
+	// until the instance is closed. On Linux, sqinn runs in its own process
+	// group, so signals from the terminal, like SIGINT on Ctrl-C, reach the
+	// program but not sqinn. Forwarding uses signal.Notify, so the program
+	// does not terminate on these signals anymore, it must handle them
+	// itself, e.g. with signal.NotifyContext.
+	// Default is nil (no forwarding).
+	ForwardSignals []os.Signal
+
+	// KillDelay is the time that Close and CloseContext wait for sqinn to
+	// exit after SIGTERM, before they send SIGKILL, see CloseContext.
+	// Default is 1 second.
+	KillDelay time.Duration
+
+	// Stale defines what Launch does with stale sqinn processes, which are
+	// sqinn processes that hold the database file open although the program
+	// that launched them has exited, e.g. after a crash. Stale processes
+	// are detected only on Linux, only for database file names (not
+	// ":memory:" or "file:" URIs), and only if the program is not init.
+	// A stale process has been reparented to init or to the child
+	// subreaper that adopts the orphans of the program, e.g. systemd --user
+	// or tini. To find that subreaper, Launch starts /bin/sh once, which
+	// leaves a short-lived orphan behind. Without /bin/sh, or if the
+	// subreaper is outside of the PID namespace, only processes reparented
+	// to init are detected. Stale processes adopted by other subreapers are
+	// not detected either.
+	// On Linux, sqinn is killed when the program exits, so new stale
+	// processes should be rare.
+	// Default is StaleIgnore (no check).
+	Stale StaleAction
 }
 
 // Prebuilt is a special path that tells sqinn-go to use an embedded
@@ -63,6 +135,19 @@ type Sqinn struct {
 	mu      sync.Mutex
 	w       *writer
 	r       *reader
+	codecs  *Codecs // can be nil
+	timefmt TimeFormat
+	noPanic bool
+	// killDelay is the time between SIGTERM and SIGKILL in CloseContext
+	killDelay time.Duration
+	broken    error // if not nil, the communication with sqinn has failed
+	closed    bool
+	// closeErr is the result of the first Close or CloseContext
+	closeErr error
+	// stderrDone is closed when the stderr of sqinn is drained
+	stderrDone chan struct{}
+	// sigc receives the forwarded signals, can be nil
+	sigc chan os.Signal
 }
 
 // Launch launches a new sqinn subprocess. The [Options] specify
@@ -82,6 +167,15 @@ func Launch(opt Options) (*Sqinn, error) {
 		tempdir = dirname
 		opt.Sqinn = filename
 	}
+	if opt.Log == nil {
+		opt.Log = func(msg string) {}
+	}
+	if err := checkStale(opt.Stale, opt.Sqinn, opt.Db, opt.Log); err != nil {
+		if tempdir != "" {
+			os.RemoveAll(tempdir)
+		}
+		return nil, err
+	}
 	var cmdArgs []string
 	cmdArgs = append(cmdArgs, "run")
 	if opt.Db != "" {
@@ -97,6 +191,7 @@ func Launch(opt Options) (*Sqinn, error) {
 		cmdArgs = append(cmdArgs, "-logstderr")
 	}
 	cmd := exec.Command(opt.Sqinn, cmdArgs...)
+	cmd.SysProcAttr = sysProcAttr()
 	stdinPipe, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -107,15 +202,41 @@ func Launch(opt Options) (*Sqinn, error) {
 		return nil, err
 	}
 	reader := newReader(stdoutPipe)
-	if opt.Log == nil {
-		opt.Log = func(msg string) {}
+	if opt.MaxFrameSize > 0 {
+		reader.maxFrame = opt.MaxFrameSize
+	}
+	if opt.MaxStringSize > 0 {
+		reader.maxString = opt.MaxStringSize
+	}
+	if opt.MaxBlobSize > 0 {
+		reader.maxBlob = opt.MaxBlobSize
 	}
-	stderrPipe, err := cmd.StderrPipe()
+	// not cmd.StderrPipe, cmd.Wait would close it before all lines are read
+	stderrDone := make(chan struct{})
+	stderrRead, stderrWrite, err := os.Pipe()
 	if err != nil {
 		opt.Log(fmt.Sprintf("cannot open sqinn stderr: %s", err))
+		close(stderrDone)
 	} else {
+		cmd.Stderr = stderrWrite
+	}
+	if err := cmd.Start(); err != nil {
+		if stderrWrite != nil {
+			stderrRead.Close()
+			stderrWrite.Close()
+		}
+		if tempdir != "" {
+			os.RemoveAll(tempdir)
+		}
+		return nil, err
+	}
+	if stderrWrite != nil {
+		// sqinn has its own copy now
+		stderrWrite.Close()
 		go func() {
-			sca := bufio.NewScanner(stderrPipe)
+			defer close(stderrDone)
+			defer stderrRead.Close()
+			sca := bufio.NewScanner(stderrRead)
 			for sca.Scan() {
 				opt.Log("[sqinn] " + sca.Text())
 			}
@@ -124,10 +245,32 @@ func Launch(opt Options) (*Sqinn, error) {
 			}
 		}()
 	}
-	if err := cmd.Start(); err != nil {
-		return nil, err
+	var sigc chan os.Signal
+	if len(opt.ForwardSignals) > 0 {
+		sigc = make(chan os.Signal, 1)
+		signal.Notify(sigc, opt.ForwardSignals...)
+		go func() {
+			for sig := range sigc {
+				cmd.Process.Signal(sig)
+			}
+		}()
 	}
-	return &Sqinn{tempdir, cmd, sync.Mutex{}, writer, reader}, nil
+	killDelay := defaultKillDelay
+	if opt.KillDelay > 0 {
+		killDelay = opt.KillDelay
+	}
+	return &Sqinn{
+		tempdir:    tempdir,
+		cmd:        cmd,
+		w:          writer,
+		r:          reader,
+		codecs:     opt.Codecs,
+		timefmt:    opt.TimeFormat,
+		noPanic:    opt.NoPanic,
+		killDelay:  killDelay,
+		stderrDone: stderrDone,
+		sigc:       sigc,
+	}, nil
 }
 
 // MustLaunch is the same as Launch except it panics on error.
@@ -153,26 +296,50 @@ type ProduceFunc func(iteration int, params []Value)
 // and the produce function.
 //
 // The nparams argument tells Exec how many parameters to bind per iteration.
-// It must be >= 0.
+// It must be >= 0. It must be the number of parameters of the SQL statement,
+// otherwise Exec returns an error without executing anything. For numbered
+// parameters like ?NNN, this is the largest parameter number, see
+// https://www.sqlite.org/c3ref/bind_parameter_count.html.
 //
 // The produce function produces parameter values. Parameter values can be
 // of the following type: int, int64, float64, string, blob or nil.
 // The length of the params argument is always nparams.
 func (sq *Sqinn) Exec(sql string, niterations, nparams int, produce ProduceFunc) error {
 	if niterations < 0 {
-		panic("invalid niterations < 0")
+		return sq.argError("Exec", "invalid niterations < 0")
 	}
 	if nparams < 0 {
-		panic("invalid nparams < 0")
+		return sq.argError("Exec", "invalid nparams < 0")
 	}
 	if nparams > 0 && produce == nil {
-		panic("invalid nparams > 0 && produce == nil")
+		return sq.argError("Exec", "invalid nparams > 0 && produce == nil")
 	}
 	if niterations == 0 {
 		return nil
 	}
+	if n := countParams(sql); n != nparams {
+		return &ArgError{"Exec", fmt.Sprintf("nparams is %d but SQL has %d parameters", nparams, n)}
+	}
 	sq.mu.Lock()
 	defer sq.mu.Unlock()
+	return sq.exec("Exec", sql, niterations, nparams, produce)
+}
+
+// exec executes sql without checking the arguments, except for the types
+// of the produced parameter values. The caller must hold sq.mu.
+func (sq *Sqinn) exec(method string, sql string, niterations, nparams int, produce ProduceFunc) error {
+	if err := sq.usable(); err != nil {
+		return err
+	}
+	nflush := sq.w.nflush
+	producing := false
+	defer func() {
+		// A panic or Goexit in produce leaves a partial request behind.
+		// A panic is not recovered, so that it keeps its original stack.
+		if producing {
+			sq.abort(nflush, errors.New("produce func did not return"))
+		}
+	}()
 	sq.w.writeByte(fcExec)       // FC_EXEC
 	sq.w.writeString(sql)        // string sql
 	sq.w.writeInt32(niterations) // int niterations
@@ -180,15 +347,20 @@ func (sq *Sqinn) Exec(sql string, niterations, nparams int, produce ProduceFunc)
 	if nparams > 0 {
 		params := make([]Value, nparams)
 		for iteration := range niterations {
+			producing = true
 			produce(iteration, params)
-			sq.writeParams(params)
+			producing = false
+			if err := sq.writeParams(params); err != nil {
+				sq.abort(nflush, err)
+				return sq.argError(method, "iteration %d: %s", iteration, err)
+			}
 			if err := sq.w.markFrame(); err != nil {
-				return err
+				return sq.fail(err)
 			}
 		}
 	}
 	if err := sq.w.flush(); err != nil {
-		return err
+		return sq.fail(err)
 	}
 	return sq.readOk()
 }
@@ -199,17 +371,45 @@ func (sq *Sqinn) MustExec(sql string, niterations, nparams int, produce ProduceF
 }
 
 // ExecParams calls Exec with the provided params.
-// The length of the params slice must be niterations * nparams.
+// The length of the params slice must be niterations * nparams,
+// otherwise ExecParams returns an error without executing anything.
 func (sq *Sqinn) ExecParams(sql string, niterations, nparams int, params []Value) error {
+	if niterations < 0 {
+		return sq.argError("ExecParams", "invalid niterations < 0")
+	}
+	if nparams < 0 {
+		return sq.argError("ExecParams", "invalid nparams < 0")
+	}
 	// check len(params)
 	if len(params) != niterations*nparams {
-		panic(fmt.Sprintf("want %d x %d params but have %d", niterations, nparams, len(params)))
+		return &ArgError{"ExecParams", fmt.Sprintf("want %d x %d params but have %d", niterations, nparams, len(params))}
 	}
 	// nothing to do if niterations is 0
 	if niterations == 0 {
 		return nil
 	}
-	return sq.Exec(sql, niterations, nparams, func(iteration int, iterationParams []Value) {
+	if n := countParams(sql); n != nparams {
+		return &ArgError{"ExecParams", fmt.Sprintf("nparams is %d but SQL has %d parameters", nparams, n)}
+	}
+	// expand list values if niterations is 1
+	if niterations == 1 {
+		var err error
+		sql, params, err = expandLists(sql, params)
+		if err != nil {
+			return err
+		}
+		nparams = len(params)
+	}
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.execParams("ExecParams", sql, niterations, nparams, params)
+}
+
+// execParams executes sql with the provided params, like ExecParams, but
+// without checking the arguments and without expanding list values.
+// The caller must hold sq.mu.
+func (sq *Sqinn) execParams(method string, sql string, niterations, nparams int, params []Value) error {
+	return sq.exec(method, sql, niterations, nparams, func(iteration int, iterationParams []Value) {
 		if len(iterationParams) != nparams {
 			panic(fmt.Sprintf("internal error: want %d iterationParams, but have only %d", nparams, len(iterationParams)))
 		}
@@ -236,6 +436,23 @@ func (sq *Sqinn) MustExecSql(sql string) {
 	must(0, sq.ExecSql(sql))
 }
 
+// execSql executes sql once, without params. The caller must hold sq.mu.
+func (sq *Sqinn) execSql(sql string) error {
+	return sq.exec("ExecSql", sql, 1, 0, nil)
+}
+
+// rollbackTo rolls back and releases the savepoint name after err has
+// occurred. It returns err, joined with the errors of the rollback, if any.
+// The caller must hold sq.mu.
+func (sq *Sqinn) rollbackTo(name string, err error) error {
+	rollbackErr := sq.execSql("ROLLBACK TO " + name)
+	releaseErr := sq.execSql("RELEASE " + name)
+	if rollbackErr != nil || releaseErr != nil {
+		return errors.Join(err, rollbackErr, releaseErr)
+	}
+	return err
+}
+
 // ConsumeFunc is a callback function that is called by Query once for each
 // result row.
 // Row is the row index, starting at 0.
@@ -246,57 +463,106 @@ type ConsumeFunc func(row int, values []Value)
 // Query executes a SQL statement and fetches the result rows.
 //
 // Params hold parameter values for the SQL statement. It can be empty.
+// Its length must be the number of parameters of the SQL statement, see
+// Exec, otherwise Query returns an error without executing anything.
 //
 // Coltypes defines the types of the columns to be fetched.
+// If a ValInt32 column holds a value that does not fit into 32 bits,
+// Query stops calling consume and returns an error.
 //
 // Consume is called exactly once for each result row.
 func (sq *Sqinn) Query(sql string, params []Value, coltypes []byte, consume ConsumeFunc) error {
-	ncols := len(coltypes)
-	if ncols == 0 {
-		panic("no coltypes")
-	}
-	if consume == nil {
-		panic("no consume func")
+	if err := sq.checkQuery("Query", coltypes, consume != nil); err != nil {
+		return err
 	}
 	for _, param := range params {
 		if param.Type == ValNull {
-			panic("nil param not allowed in Query")
+			return sq.argError("Query", "nil param not allowed in Query")
 		}
 	}
+	if n := countParams(sql); n != len(params) {
+		return &ArgError{"Query", fmt.Sprintf("have %d params but SQL has %d parameters", len(params), n)}
+	}
+	sql, params, err := expandLists(sql, params)
+	if err != nil {
+		return err
+	}
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.query("Query", sql, params, coltypes, consume)
+}
+
+// checkQuery checks the coltypes and consume func arguments of method.
+func (sq *Sqinn) checkQuery(method string, coltypes []byte, hasConsume bool) error {
+	if len(coltypes) == 0 {
+		return sq.argError(method, "no coltypes")
+	}
+	if !hasConsume {
+		return sq.argError(method, "no consume func")
+	}
 	for _, coltype := range coltypes {
 		if coltype == ValNull {
-			panic("coltype ValNull not allowed in Query")
+			return sq.argError(method, "coltype ValNull not allowed in %s", method)
 		}
 	}
-	sq.mu.Lock()
-	defer sq.mu.Unlock()
-	sq.w.writeByte(fcQuery)        // FC_QUERY
-	sq.w.writeString(sql)          // string sql
-	sq.w.writeInt32(len(params))   // int nparams
-	sq.writeParams(params)         // []value params
+	return nil
+}
+
+// query executes a query without checking the arguments, except for the
+// types of the parameter values. The caller must hold sq.mu.
+func (sq *Sqinn) query(method string, sql string, params []Value, coltypes []byte, consume ConsumeFunc) error {
+	if err := sq.usable(); err != nil {
+		return err
+	}
+	ncols := len(coltypes)
+	nflush := sq.w.nflush
+	sq.w.writeByte(fcQuery)      // FC_QUERY
+	sq.w.writeString(sql)        // string sql
+	sq.w.writeInt32(len(params)) // int nparams
+	// []value params
+	if err := sq.writeParams(params); err != nil {
+		sq.abort(nflush, err)
+		return sq.argError(method, "%s", err)
+	}
 	sq.w.writeInt32(len(coltypes)) // int ncols
 	for _, vt := range coltypes {  // []byte coltypes
+		if vt == ValInt32 {
+			vt = ValInt64 // fetch as int64, so that we can detect int32 overflows
+		}
 		sq.w.writeByte(vt)
 	}
 	if err := sq.w.flush(); err != nil {
-		return err
+		return sq.fail(err)
 	}
+	consuming := false
+	defer func() {
+		// A panic or Goexit in consume leaves the rest of the response
+		// unread. A panic is not recovered, so that it keeps its original
+		// stack.
+		if consuming {
+			sq.fail(errors.New("consume func did not return"))
+		}
+	}()
 	values := make([]Value, ncols)
+	var overflowErr error
 	irow := -1
 	for {
 		irow++
 		hasRow, err := sq.r.readByte()
 		if err != nil {
-			return err
+			return sq.fail(err)
 		}
 		if hasRow == 0 {
 			break // no more rows
 		}
+		if hasRow != 1 {
+			return sq.fail(sq.r.errorf(-1, "invalid row marker %d", hasRow))
+		}
 		for icol := range coltypes {
 			var val Value
 			val.Type, err = sq.r.readByte()
 			if err != nil {
-				return err
+				return sq.fail(err)
 			}
 			switch val.Type {
 			case ValNull:
@@ -312,16 +578,31 @@ func (sq *Sqinn) Query(sql string, params []Value, coltypes []byte, consume Cons
 			case ValBlob:
 				val.Blob, err = sq.r.readBlob()
 			default:
-				panic("invalid value type")
+				return sq.fail(sq.r.errorf(-1, "invalid value type %d", val.Type))
 			}
 			if err != nil {
-				return err
+				return sq.fail(err)
+			}
+			if coltypes[icol] == ValInt32 && val.Type == ValInt64 {
+				if !fitsInt32(val.Int64) {
+					if overflowErr == nil {
+						overflowErr = fmt.Errorf("row %d column %d: value %d overflows ValInt32", irow, icol, val.Int64)
+					}
+				}
+				val = Int32Value(int(val.Int64))
 			}
 			values[icol] = val
 		}
-		consume(irow, values)
+		if overflowErr == nil {
+			consuming = true
+			consume(irow, values)
+			consuming = false
+		}
 	}
-	return sq.readOk()
+	if err := sq.readOk(); err != nil {
+		return err
+	}
+	return overflowErr
 }
 
 // MustQuery is the same as Query except it panics on error.
@@ -345,43 +626,166 @@ func (sq *Sqinn) MustQueryRows(sql string, params []Value, coltypes []byte) [][]
 }
 
 // Close closes the database and terminates the sqinn process.
+// It is CloseContext with a timeout of 5 seconds.
 func (sq *Sqinn) Close() error {
-	sq.mu.Lock()
-	defer sq.mu.Unlock()
-	sq.w.writeByte(fcQuit)
-	if err := sq.w.flush(); err != nil {
-		return fmt.Errorf("Close: %w", err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return sq.CloseContext(ctx)
+}
+
+// defaultKillDelay is the default of Options.KillDelay.
+const defaultKillDelay = 1 * time.Second
+
+// CloseContext closes the database and terminates the sqinn process.
+//
+// It asks sqinn to quit and waits for it to exit. If ctx is done before
+// that, or if the instance is broken, CloseContext sends SIGTERM to sqinn,
+// and if sqinn does not exit within Options.KillDelay, SIGKILL. On Windows,
+// it kills sqinn right away. In any case, CloseContext waits for sqinn to
+// exit, forwards the remaining log lines of sqinn, and removes the prebuilt
+// tempdir.
+//
+// If another call is pending when ctx is done, CloseContext terminates
+// sqinn the same way, which makes the pending call fail. If the pending
+// call still does not return within Options.KillDelay after SIGKILL, e.g.
+// because its ProduceFunc or ConsumeFunc hangs, CloseContext gives up and
+// returns an error that wraps ctx.Err(). The instance is not closed then,
+// and a later Close can finish the job.
+//
+// If sqinn does not exit normally, the returned error wraps an
+// *exec.ExitError with the exit status. CloseContext is safe to call more
+// than once, further calls return the result of the first call. All other
+// calls after CloseContext return ErrClosed.
+func (sq *Sqinn) CloseContext(ctx context.Context) error {
+	locked := make(chan struct{})
+	abandoned := make(chan struct{})
+	go func() {
+		sq.mu.Lock()
+		select {
+		case locked <- struct{}{}:
+		case <-abandoned:
+			sq.mu.Unlock()
+		}
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		// a pending request hangs, terminating sqinn makes it fail
+		if !sq.awaitLock(locked) {
+			close(abandoned)
+			return fmt.Errorf("Close: %w", ctx.Err())
+		}
 	}
-	if err := sq.readOk(); err != nil {
-		return fmt.Errorf("Close: %w", err)
+	defer sq.mu.Unlock()
+	if sq.closed {
+		return sq.closeErr
 	}
-	sq.cmd.WaitDelay = 5 * time.Second
-	if err := sq.cmd.Wait(); err != nil {
-		return fmt.Errorf("Close: %w", err)
+	sq.closed = true
+	if sq.sigc != nil {
+		// no more sends after Stop returns
+		signal.Stop(sq.sigc)
+		close(sq.sigc)
 	}
+	sq.closeErr = sq.stop(ctx)
 	if sq.tempdir != "" {
 		os.RemoveAll(sq.tempdir)
 	}
+	return sq.closeErr
+}
+
+// awaitLock sends SIGTERM and, after sq.killDelay, SIGKILL to sqinn, so
+// that a pending request fails and releases sq.mu. It reports whether locked
+// was received within sq.killDelay after SIGKILL.
+func (sq *Sqinn) awaitLock(locked <-chan struct{}) bool {
+	for _, send := range []func(*os.Process) error{terminate, (*os.Process).Kill} {
+		send(sq.cmd.Process)
+		select {
+		case <-locked:
+			return true
+		case <-time.After(sq.killDelay):
+		}
+	}
+	return false
+}
+
+// stop terminates the sqinn process and waits for it to exit, escalating
+// from quit to SIGTERM to SIGKILL. The caller must hold sq.mu.
+func (sq *Sqinn) stop(ctx context.Context) error {
+	broken := sq.broken != nil
+	exited := make(chan error, 1)
+	go func() {
+		var quitErr error
+		if !broken {
+			sq.w.writeByte(fcQuit)
+			quitErr = sq.w.flush()
+			if quitErr == nil {
+				quitErr = sq.readOk()
+			}
+		}
+		err := sq.cmd.Wait()
+		<-sq.stderrDone
+		if err == nil && quitErr != nil {
+			err = quitErr
+		}
+		exited <- err
+	}()
+	var err error
+	if !broken {
+		select {
+		case err = <-exited:
+			return closeError(err)
+		case <-ctx.Done():
+		}
+	}
+	// sqinn might wait for the rest of a request, it cannot quit
+	if terminate(sq.cmd.Process) == nil {
+		select {
+		case err = <-exited:
+			return closeError(err)
+		case <-time.After(sq.killDelay):
+		}
+	}
+	sq.cmd.Process.Kill()
+	return closeError(<-exited)
+}
+
+func closeError(err error) error {
+	if err != nil {
+		return fmt.Errorf("Close: %w", err)
+	}
 	return nil
 }
 
+// readOk reads the status of a response. Errors that leave the
+// communication in an unknown state mark the instance as broken.
 func (sq *Sqinn) readOk() error {
 	ok, err := sq.r.readByte()
 	if err != nil {
-		return err
+		return sq.fail(err)
 	}
 	if ok == 1 {
 		return nil
 	}
+	if ok != 0 {
+		return sq.fail(sq.r.errorf(-1, "invalid status %d", ok))
+	}
 	errmsg, err := sq.r.readString()
 	if err != nil {
-		return err
+		return sq.fail(err)
 	}
 	return fmt.Errorf("sqinn: %s", errmsg)
 }
 
-func (sq *Sqinn) writeParams(params []Value) {
+// writeParams writes parameter values. It returns an error for a value of
+// unknown type, e.g. ValList. The values before it have been written then.
+func (sq *Sqinn) writeParams(params []Value) error {
 	for _, p := range params {
+		if p.Type == ValInt32 && !fitsInt32(int64(p.Int32)) {
+			p = Int64Value(int64(p.Int32)) // widen, do not truncate
+		}
+		if p.Type > ValBlob {
+			return fmt.Errorf("unknown param value type %d", p.Type)
+		}
 		sq.w.writeByte(p.Type)
 		switch p.Type {
 		case ValNull:
@@ -396,10 +800,9 @@ func (sq *Sqinn) writeParams(params []Value) {
 			sq.w.writeString(p.String)
 		case ValBlob:
 			sq.w.writeBlob(p.Blob)
-		default:
-			panic(fmt.Sprintf("unknown param value type %T", p.Type))
 		}
 	}
+	return nil
 }
 
 const (
@@ -416,12 +819,14 @@ type Value struct {
 	Double float64 // For ValDouble
 	String string  // For ValString
 	Blob   []byte  // For ValBlob
+	List   []Value // For ValList
 }
 
 // NullValue creates a Value with type ValNull.
 func NullValue() Value { return Value{Type: ValNull} }
 
 // Int32Value creates a Value with type ValInt32.
+// If v does not fit into 32 bits, it is sent to sqinn as ValInt64.
 func Int32Value(v int) Value { return Value{Type: ValInt32, Int32: v} }
 
 // Int64Value creates a Value with type ValInt64.
@@ -436,6 +841,9 @@ func StringValue(v string) Value { return Value{Type: ValString, String: v} }
 // BlobValue creates a Value with type ValBlob.
 func BlobValue(v []byte) Value { return Value{Type: ValBlob, Blob: v} }
 
+// List creates a Value with type ValList. See ValList for details.
+func List(values ...Value) Value { return Value{Type: ValList, List: values} }
+
 // Value types.
 const (
 	ValNull   byte = 0
@@ -444,122 +852,185 @@ const (
 	ValDouble byte = 3
 	ValString byte = 4
 	ValBlob   byte = 5
+
+	// ValList is a list of values that is expanded into a list of
+	// parameters, e.g. for "WHERE id IN (?)". It is not a SQLite type and is
+	// never sent to sqinn. List values are allowed as params in Query and
+	// QueryRows, and in ExecParams if niterations is 1. The SQL must
+	// use plain ? placeholders, and a list placeholder must be the only
+	// element in its parentheses.
+	// An empty list expands to "IN ()", which is false for all rows.
+	// Large lists of more than 1000 values are not expanded into parameters
+	// but are sent as a JSON array and read with json_each, and therefore
+	// must not contain blob values.
+	ValList byte = 6
 )
 
 // A Scanner scans Values.
+//
+// A Scanner does not panic if there are no more values or if a value
+// has an unexpected type. Instead, it records the first such error,
+// which can be retrieved with Err.
 type Scanner struct {
-	values []Value
-	i      int
+	values  []Value
+	i       int
+	codecs  *Codecs    // for Decode, can be nil
+	timefmt TimeFormat // for Scan with *time.Time
+	err     error      // first error
 }
 
 // Scan creates a Scanner with the provided values.
 func Scan(values []Value) *Scanner {
-	return &Scanner{values, -1}
+	return &Scanner{values, -1, nil, TimeText, nil}
+}
+
+// Err returns the first error that occurred while scanning, or nil.
+// The error is a *ScanError.
+func (s *Scanner) Err() error {
+	return s.err
 }
 
 // Next returns the next Value.
+// If there are no more values, it returns a NULL Value and records an error.
 func (s *Scanner) Next() Value {
 	s.i++
+	if s.i >= len(s.values) {
+		s.fail("no more values, have only %d", len(s.values))
+		return NullValue()
+	}
 	return s.values[s.i]
 }
 
+// next returns the next Value and records an error if it is neither
+// NULL nor of type vt. If notNull is true, NULL values are recorded
+// as error, too. Integer values are converted between ValInt32 and
+// ValInt64 if they fit. For other types, next returns a zero Value of
+// the other type, so that it can be told apart from NULL.
+func (s *Scanner) next(vt byte, notNull bool) Value {
+	v := s.Next()
+	if v.Type == ValNull {
+		if notNull && s.i < len(s.values) {
+			s.fail("unexpected NULL")
+		}
+		return v
+	}
+	switch {
+	case v.Type == vt:
+		return v
+	case vt == ValInt64 && v.Type == ValInt32:
+		return Int64Value(int64(v.Int32))
+	case vt == ValInt32 && v.Type == ValInt64:
+		if fitsInt32(v.Int64) {
+			return Int32Value(int(v.Int64))
+		}
+		s.fail("value %d overflows ValInt32", v.Int64)
+	default:
+		s.fail("want %s but have %s", valTypeName(vt), valTypeName(v.Type))
+	}
+	return Value{Type: v.Type}
+}
+
+// fail records an error for the current column, if no error was recorded before.
+func (s *Scanner) fail(format string, args ...any) {
+	if s.err == nil {
+		s.err = &ScanError{s.i, fmt.Sprintf(format, args...), nil}
+	}
+}
+
 // NextInt32 returns the next values Int32 field and true if the value was not NULL, false if it was NULL.
+// A ValInt64 value is converted if it fits into 32 bits. For any other
+// value, NextInt32 returns 0 and true, and records an error.
 func (s *Scanner) NextInt32() (int, bool) {
-	v := s.Next()
+	v := s.next(ValInt32, false)
 	return v.Int32, v.Type != ValNull
 }
 
 // NextInt64 returns the next values Int64 field and true if the value was not NULL, false if it was NULL.
+// A ValInt32 value is converted. For any other value, NextInt64 returns 0
+// and true, and records an error.
 func (s *Scanner) NextInt64() (int64, bool) {
-	v := s.Next()
+	v := s.next(ValInt64, false)
 	return v.Int64, v.Type != ValNull
 }
 
 // NextDouble returns the next values Double field and true if the value was not NULL, false if it was NULL.
 func (s *Scanner) NextDouble() (float64, bool) {
-	v := s.Next()
+	v := s.next(ValDouble, false)
 	return v.Double, v.Type != ValNull
 }
 
 // NextString returns the next values String field and true if the value was not NULL, false if it was NULL.
 func (s *Scanner) NextString() (string, bool) {
-	v := s.Next()
+	v := s.next(ValString, false)
 	return v.String, v.Type != ValNull
 }
 
 // NextBlob returns the next values Blob field and true if the value was not NULL, false if it was NULL.
 func (s *Scanner) NextBlob() ([]byte, bool) {
-	v := s.Next()
+	v := s.next(ValBlob, false)
 	return v.Blob, v.Type != ValNull
 }
 
-// Int32 returns the next values Int32 field. It does not check for NULL.
-func (s *Scanner) Int32() int { return s.Next().Int32 }
+// Int32 returns the next values Int32 field. It returns 0 for NULL and records an error.
+// A ValInt64 value is converted if it fits into 32 bits. For any other
+// value, Int32 returns 0 and records an error. Earlier versions did not
+// record errors and returned 0 for all values that were not ValInt32.
+func (s *Scanner) Int32() int { return s.next(ValInt32, true).Int32 }
 
-// Int64 returns the next values Int64 field. It does not check for NULL.
-func (s *Scanner) Int64() int64 { return s.Next().Int64 }
+// Int64 returns the next values Int64 field. It returns 0 for NULL and records an error.
+// A ValInt32 value is converted. For any other value, Int64 returns 0 and
+// records an error.
+func (s *Scanner) Int64() int64 { return s.next(ValInt64, true).Int64 }
 
-// Double returns the next values Double field. It does not check for NULL.
-func (s *Scanner) Double() float64 { return s.Next().Double }
+// Double returns the next values Double field. It returns 0 for NULL and records an error.
+func (s *Scanner) Double() float64 { return s.next(ValDouble, true).Double }
 
-// String returns the next values String field. It does not check for NULL.
-func (s *Scanner) String() string { return s.Next().String }
+// String returns the next values String field. It returns "" for NULL and records an error.
+func (s *Scanner) String() string { return s.next(ValString, true).String }
 
-// Blob returns the next values Blob field. It does not check for NULL.
-func (s *Scanner) Blob() []byte { return s.Next().Blob }
+// Blob returns the next values Blob field. It returns nil for NULL and records an error.
+func (s *Scanner) Blob() []byte { return s.next(ValBlob, true).Blob }
 
 // Bind converts Go types to sqinn Values. It supports the following Go types
 //
 //	nil  -> ValNull
-//	int  -> ValInt32
+//	int8, int16, int32, uint8, uint16  -> ValInt32
+//	int, uint, uint32, uint64  -> ValInt32, or ValInt64 if it does not fit into 32 bits
 //	int64  -> ValInt64
-//	float64  -> ValDouble
+//	float32, float64  -> ValDouble
+//	bool  -> ValInt32 (0 or 1)
 //	string  -> ValString
 //	[]byte  -> ValBlob
+//	time.Time  -> ValString (see TimeText)
+//	Value  -> the Value itself
+//	driver.Valuer  -> the bound result of its Value method, e.g. for sql.NullString
+//	json.Marshaler  -> ValString
+//	pointers  -> ValNull if nil, otherwise the bound pointee
 //
-// For any other Go type, it panics.
+// Types whose underlying type is one of the above, e.g. "type Status int",
+// are bound like their underlying type.
+// For any other Go type, it panics. See BindE for a variant that
+// returns an error, Sqinn.Bind for the time format of Options.TimeFormat,
+// and Binder for more options.
 func Bind(params []any) []Value {
-	if len(params) == 0 {
-		return nil
-	}
-	values := make([]Value, len(params))
-	for i, p := range params {
-		if p == nil {
-			values[i].Type = ValNull
-			continue // with next param
-		}
-		switch v := p.(type) {
-		case int:
-			values[i].Type = ValInt32
-			values[i].Int32 = v
-		case int64:
-			values[i].Type = ValInt64
-			values[i].Int64 = v
-		case float64:
-			values[i].Type = ValDouble
-			values[i].Double = v
-		case string:
-			values[i].Type = ValString
-			values[i].String = v
-		case []byte:
-			values[i].Type = ValBlob
-			values[i].Blob = v
-		default:
-			panic("sqinn.Bind(): wrong Go type")
-		}
-	}
-	return values
+	return must(BindE(params))
+}
+
+// BindE is like Bind but returns an error instead of panicking.
+func BindE(params []any) ([]Value, error) {
+	return Binder{}.Bind(params)
 }
 
 // A writer encodes values into bytes and writes them to a io.Writer.
 type writer struct {
-	w   io.Writer
-	buf []byte
-	wp  int // write pointer
+	w      io.Writer
+	buf    []byte
+	wp     int // write pointer
+	nflush int // number of flushes, so that callers can detect if a request was partially sent
 }
 
 func newWriter(w io.Writer) *writer {
-	return &writer{w, make([]byte, 1024*1024), 0}
+	return &writer{w, make([]byte, 1024*1024), 0, 0}
 }
 
 func (x *writer) append(p []byte) {
@@ -615,6 +1086,7 @@ func (x *writer) flush() error {
 	if x.wp == 0 {
 		return nil
 	}
+	x.nflush++
 	lbuf := encodeInt32(x.wp)
 	// log.Printf("to sqinn: %d len bytes: %v", len(lbuf), lbuf)
 	n, err := x.w.Write(lbuf)
@@ -637,16 +1109,38 @@ func (x *writer) flush() error {
 }
 
 // A reader reads bytes from a io.Reader and decodes them into values.
+//
+// The bytes come in frames, each frame is a 4-byte length followed by
+// that many bytes. A value never spans frames. The reader checks all
+// lengths against the maximum sizes before it reads any data, and reports
+// malformed input as *ProtocolError.
 type reader struct {
-	r    io.Reader
-	buf  *bytes.Buffer
-	buf1 []byte
-	buf4 []byte
-	buf8 []byte
+	r         io.Reader
+	buf       *bytes.Buffer
+	buf1      []byte
+	buf4      []byte
+	buf8      []byte
+	off       int64 // stream offset of the next unread byte
+	maxFrame  int
+	maxString int
+	maxBlob   int
 }
 
+// Default maximum sizes of a reader, see Options.
+const (
+	defaultMaxFrameSize  = 64 << 20
+	defaultMaxStringSize = 1_000_000_000
+	defaultMaxBlobSize   = 1_000_000_000
+)
+
 func newReader(r io.Reader) *reader {
-	return &reader{r, bytes.NewBuffer(nil), make([]byte, 1), make([]byte, 4), make([]byte, 8)}
+	return &reader{r, bytes.NewBuffer(nil), make([]byte, 1), make([]byte, 4), make([]byte, 8), 0, defaultMaxFrameSize, defaultMaxStringSize, defaultMaxBlobSize}
+}
+
+// errorf returns a *ProtocolError for the data at offset delta from the
+// next unread byte, e.g. -1 for the last byte read.
+func (x *reader) errorf(delta int64, format string, args ...any) error {
+	return &ProtocolError{x.off + delta, fmt.Sprintf(format, args...)}
 }
 
 func (x *reader) readBytes(n int) ([]byte, error) {
@@ -656,15 +1150,25 @@ func (x *reader) readBytes(n int) ([]byte, error) {
 		if _, err := io.ReadFull(x.r, x.buf4); err != nil {
 			return nil, err
 		}
-		n := decodeInt32(x.buf4)
-		// log.Printf("read %d bytes from sqinn", n)
-		if _, err := io.CopyN(x.buf, x.r, int64(n)); err != nil {
+		x.off += 4
+		size := decodeInt32(x.buf4)
+		if size <= 0 {
+			return nil, x.errorf(-4, "invalid frame length %d", size)
+		}
+		if size > x.maxFrame {
+			return nil, x.errorf(-4, "frame length %d exceeds maximum %d", size, x.maxFrame)
+		}
+		// CopyN grows buf with the data actually read, not with size
+		if _, err := io.CopyN(x.buf, x.r, int64(size)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
 			return nil, err
 		}
 	}
 	avail = x.buf.Len()
 	if avail < n {
-		return nil, fmt.Errorf("readBytes: want at least %d bytes available but have %d", n, avail)
+		return nil, x.errorf(0, "want %d bytes but frame has %d left", n, avail)
 	}
 	var buf []byte
 	switch n {
@@ -680,6 +1184,7 @@ func (x *reader) readBytes(n int) ([]byte, error) {
 	if _, err := x.buf.Read(buf); err != nil {
 		return nil, err
 	}
+	x.off += int64(n)
 	return buf, nil
 }
 
@@ -716,18 +1221,25 @@ func (x *reader) readDouble() (float64, error) {
 }
 
 func (x *reader) readString() (string, error) {
-	buf, err := x.readBlob()
+	// the length includes the null terminator
+	length, err := x.readInt32()
 	if err != nil {
 		return "", err
 	}
-	n := len(buf)
-	if n < 1 {
-		return "", fmt.Errorf("readString: invalid string length %d", n)
+	if length < 1 {
+		return "", x.errorf(-4, "invalid string length %d", length)
+	}
+	if length-1 > x.maxString {
+		return "", x.errorf(-4, "string length %d exceeds maximum %d", length-1, x.maxString)
+	}
+	buf, err := x.readBytes(length)
+	if err != nil {
+		return "", err
 	}
-	if buf[n-1] != 0 {
-		return "", fmt.Errorf("readString: string must be null-terminated")
+	if buf[length-1] != 0 {
+		return "", x.errorf(-1, "string must be null-terminated")
 	}
-	return string(buf[:n-1]), nil
+	return string(buf[:length-1]), nil
 }
 
 func (x *reader) readBlob() ([]byte, error) {
@@ -735,6 +1247,12 @@ func (x *reader) readBlob() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if length < 0 {
+		return nil, x.errorf(-4, "invalid blob length %d", length)
+	}
+	if length > x.maxBlob {
+		return nil, x.errorf(-4, "blob length %d exceeds maximum %d", length, x.maxBlob)
+	}
 	if length == 0 {
 		return nil, nil
 	}
@@ -816,6 +1334,11 @@ func decodeDouble(p []byte) float64 {
 
 // util
 
+// fitsInt32 reports whether v can be represented as a 32-bit signed integer.
+func fitsInt32(v int64) bool {
+	return math.MinInt32 <= v && v <= math.MaxInt32
+}
+
 func must[V any](v V, err error) V {
 	if err != nil {
 		panic(err)
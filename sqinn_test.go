@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -186,13 +188,20 @@ func TestSqinn(t *testing.T) {
 		isEq(t, 1, values[0].Int32)
 	}))
 	//
-	// to few params, but that's ok
-	isNoErr(t, sq.Query("SELECT COUNT(*) FROM users WHERE i=?", nil, []byte{ValInt32}, func(row int, values []Value) {
-		isEq(t, 0, row)
-		isEq(t, 1, len(values))
-		isEq(t, ValInt32, values[0].Type)
-		isEq(t, 0, values[0].Int32)
-	}))
+	// too few or too many params
+	err = sq.Query("SELECT COUNT(*) FROM users WHERE i=?", nil, []byte{ValInt32}, func(row int, values []Value) {})
+	isErr(t, err, "Query: have 0 params but SQL has 1 parameters")
+	err = sq.Query("SELECT COUNT(*) FROM users WHERE i=?", []Value{Int32Value(1), Int32Value(2)}, []byte{ValInt32}, func(row int, values []Value) {})
+	isErr(t, err, "Query: have 2 params but SQL has 1 parameters")
+	err = sq.Exec("UPDATE users SET t=? WHERE i=?", 1, 1, func(iteration int, params []Value) {})
+	isErr(t, err, "Exec: nparams is 1 but SQL has 2 parameters")
+	err = sq.ExecSql("DELETE FROM users WHERE i=?")
+	isErr(t, err, "Exec: nparams is 0 but SQL has 1 parameters")
+	err = sq.ExecParams("DELETE FROM users WHERE i=:i OR j=:i", 1, 2, []Value{Int32Value(1), Int32Value(1)})
+	isErr(t, err, "ExecParams: nparams is 2 but SQL has 1 parameters")
+	// parameters are counted like SQLite does, and only in the first statement
+	isNoErr(t, sq.ExecParams("DELETE FROM users WHERE i=:i OR j=:i OR t=?3 OR t=? OR t='?' -- ?", 1, 4, []Value{Int32Value(0), NullValue(), NullValue(), NullValue()}))
+	isNoErr(t, sq.ExecSql("SELECT 1; SELECT ?"))
 	//
 	// ExecRaw must panic if arguments are wrong
 	isPanic(t, "invalid niterations < 0", func() {
@@ -205,10 +214,9 @@ func TestSqinn(t *testing.T) {
 		sq.Exec("DELETE FROM users WHERE i=131313", 1, 1, nil)
 	})
 	//
-	// Exec must panic if arguments are wrong
-	isPanic(t, "want 1 x 1 params but have 2", func() {
-		sq.ExecParams("DELETE FROM users WHERE i=?", 1, 1, []Value{Int32Value(0), NullValue()})
-	})
+	// ExecParams must fail if params are wrong
+	err = sq.ExecParams("DELETE FROM users WHERE i=?", 1, 1, []Value{Int32Value(0), NullValue()})
+	isErr(t, err, "ExecParams: want 1 x 1 params but have 2")
 	//
 	// QueryRaw must panic if arguments are wrong
 	isPanic(t, "coltype ValNull not allowed in Query", func() {
@@ -230,7 +238,7 @@ func TestSqinn(t *testing.T) {
 	err = sq.Query("SELECT COUNT(*) FROM users WHERE hoob = 1", nil, []byte{ValInt32}, func(row int, values []Value) {})
 	isErr(t, err, "sqinn: no such column: hoob")
 	err = sq.Query("SELECT COUNT(*) FROM users", []Value{Int32Value(1)}, []byte{ValInt32, ValInt32}, func(row int, values []Value) {})
-	isErr(t, err, "sqinn: column index out of range")
+	isErr(t, err, "Query: have 1 params but SQL has 0 parameters")
 }
 
 func TestSqinnLog(t *testing.T) {
@@ -294,6 +302,44 @@ func TestSqinnBadPath(t *testing.T) {
 		"invalid errmsg %q", errmsg)
 }
 
+func TestInt32Overflow(t *testing.T) {
+	sq := MustLaunch(Options{})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	sq.MustExecSql("CREATE TABLE numbers (i INTEGER)")
+	// int32 values that do not fit into 32 bits are widened
+	sq.MustExecParams("INSERT INTO numbers (i) VALUES (?)", 4, 1, []Value{
+		Int32Value(math.MinInt32),
+		Int32Value(math.MaxInt32),
+		Int32Value(math.MaxInt32 + 1),
+		Int32Value(-1 << 40),
+	})
+	rows := sq.MustQueryRows("SELECT i FROM numbers ORDER BY rowid", nil, []byte{ValInt64})
+	isEq(t, 4, len(rows))
+	isEq(t, math.MinInt32, rows[0][0].Int64)
+	isEq(t, math.MaxInt32, rows[1][0].Int64)
+	isEq(t, math.MaxInt32+1, rows[2][0].Int64)
+	isEq(t, -1<<40, rows[3][0].Int64)
+	// reading values that do not fit into 32 bits as ValInt32 is an error
+	rows = sq.MustQueryRows("SELECT i FROM numbers WHERE rowid <= 2 ORDER BY rowid", nil, []byte{ValInt32})
+	isEq(t, 2, len(rows))
+	isEq(t, ValInt32, rows[0][0].Type)
+	isEq(t, math.MinInt32, rows[0][0].Int32)
+	isEq(t, ValInt32, rows[1][0].Type)
+	isEq(t, math.MaxInt32, rows[1][0].Int32)
+	var nrows int
+	err := sq.Query("SELECT NULL, i FROM numbers ORDER BY rowid", nil, []byte{ValInt32, ValInt32}, func(row int, values []Value) {
+		isEq(t, ValNull, values[0].Type)
+		nrows++
+	})
+	isErr(t, err, "row 2 column 1: value 2147483648 overflows ValInt32")
+	isEq(t, 2, nrows)
+	// the instance is still usable
+	rows = sq.MustQueryRows("SELECT COUNT(*) FROM numbers", nil, []byte{ValInt32})
+	isEq(t, 4, rows[0][0].Int32)
+}
+
 func TestScanner(t *testing.T) {
 	sc := Scan([]Value{
 		NullValue(),
@@ -367,8 +413,18 @@ func TestBind(t *testing.T) {
 	values = Bind(nil)
 	isEq(t, 0, len(values))
 	//
-	isPanic(t, "sqinn.Bind(): wrong Go type", func() {
-		Bind([]any{false})
+	values = Bind([]any{math.MaxInt32, math.MaxInt32 + 1, math.MinInt32, math.MinInt32 - 1})
+	isEq(t, ValInt32, values[0].Type)
+	isEq(t, math.MaxInt32, values[0].Int32)
+	isEq(t, ValInt64, values[1].Type)
+	isEq(t, math.MaxInt32+1, values[1].Int64)
+	isEq(t, ValInt32, values[2].Type)
+	isEq(t, math.MinInt32, values[2].Int32)
+	isEq(t, ValInt64, values[3].Type)
+	isEq(t, math.MinInt32-1, values[3].Int64)
+	//
+	isPanic(t, "sqinn.Bind(): param 1: wrong Go type struct {}", func() {
+		Bind([]any{false, struct{}{}})
 	})
 }
 
@@ -758,9 +814,10 @@ func TestReadErrors(t *testing.T) {
 	})
 	r := newReader(rb)
 	_, err := r.readInt64()
-	isErr(t, err, "readBytes: want at least 8 bytes available but have 4")
+	isErr(t, err, "protocol error at offset 4: want 8 bytes but frame has 4 left")
+	isTrue(t, errors.Is(err, ErrProtocol), "want ErrProtocol")
 	_, err = r.readString()
-	isErr(t, err, "readString: invalid string length 0")
+	isErr(t, err, "protocol error at offset 4: invalid string length 0")
 	// read from memory
 	rb = bytes.NewBuffer([]byte{
 		0x00, 0x00, // short frame length
@@ -769,12 +826,34 @@ func TestReadErrors(t *testing.T) {
 	_, err = r.readInt64()
 	isErr(t, err, "unexpected EOF")
 	// read from memory
+	rb = bytes.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x08, // frame length
+		0x00, 0x00, // short frame payload
+	})
+	r = newReader(rb)
+	_, err = r.readInt64()
+	isErr(t, err, "unexpected EOF")
+	// read from memory
 	rb = bytes.NewBuffer([]byte{
 		0xFF, 0xFF, 0xFF, 0xFF, // negative frame length
 	})
 	r = newReader(rb)
 	_, err = r.readInt64()
-	isErr(t, err, "readBytes: want at least 8 bytes available but have 0")
+	isErr(t, err, "protocol error at offset 0: invalid frame length -1")
+	// read from memory
+	rb = bytes.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x00, // empty frame
+	})
+	r = newReader(rb)
+	_, err = r.readByte()
+	isErr(t, err, "protocol error at offset 0: invalid frame length 0")
+	// read from memory
+	rb = bytes.NewBuffer([]byte{
+		0x04, 0x00, 0x00, 0x01, // frame length 64 MB + 1
+	})
+	r = newReader(rb)
+	_, err = r.readByte()
+	isErr(t, err, "protocol error at offset 0: frame length 67108865 exceeds maximum 67108864")
 	// read from memory
 	rb = bytes.NewBuffer([]byte{
 		0x00, 0x00, 0x00, 0x07, // frame length
@@ -783,7 +862,7 @@ func TestReadErrors(t *testing.T) {
 	})
 	r = newReader(rb)
 	_, err = r.readString()
-	isErr(t, err, "readString: string must be null-terminated")
+	isErr(t, err, "protocol error at offset 10: string must be null-terminated")
 	_, err = r.readInt32()
 	isErr(t, err, "EOF")
 	_, err = r.readInt64()
@@ -794,6 +873,158 @@ func TestReadErrors(t *testing.T) {
 	isErr(t, err, "EOF")
 	_, err = r.readBlob()
 	isErr(t, err, "EOF")
+	// read from memory
+	rb = bytes.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x0C, // frame length
+		0x00, 0x00, 0x00, 0x01, // int32
+		0xFF, 0xFF, 0xFF, 0xFE, // negative blob len
+		0x80, 0x00, 0x00, 0x00, // negative string len
+	})
+	r = newReader(rb)
+	_, err = r.readInt32()
+	isNoErr(t, err)
+	_, err = r.readBlob()
+	isErr(t, err, "protocol error at offset 8: invalid blob length -2")
+	_, err = r.readString()
+	isErr(t, err, "protocol error at offset 12: invalid string length -2147483648")
+	// maximum sizes
+	rb = bytes.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x0A, // frame length
+		0x00, 0x00, 0x00, 0x03, // string len
+		0x41, 0x41, 0x00, // string data
+		0x00, 0x00, 0x00, // ...
+	})
+	r = newReader(rb)
+	r.maxFrame = 9
+	_, err = r.readString()
+	isErr(t, err, "protocol error at offset 0: frame length 10 exceeds maximum 9")
+	response := []byte{
+		0x00, 0x00, 0x00, 0x0E, // frame length
+		0x00, 0x00, 0x00, 0x03, // string len
+		0x41, 0x41, 0x00, // string data
+		0x00, 0x00, 0x00, 0x03, // blob len
+		0x01, 0x02, 0x03, // blob data
+	}
+	r = newReader(bytes.NewBuffer(response))
+	r.maxString = 1
+	_, err = r.readString()
+	isErr(t, err, "protocol error at offset 4: string length 2 exceeds maximum 1")
+	r = newReader(bytes.NewBuffer(response))
+	r.maxString = 2
+	r.maxBlob = 2
+	s, err := r.readString()
+	isNoErr(t, err)
+	isEq(t, "AA", s)
+	_, err = r.readBlob()
+	isErr(t, err, "protocol error at offset 11: blob length 3 exceeds maximum 2")
+}
+
+// frame encodes a response frame.
+func frame(payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	return append(encodeInt32(len(data)), data...)
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5}, frame([]byte{7}, encodeInt32(42), encodeInt64(43), encodeDouble(4.4), encodeInt32(2), []byte("A\x00"), encodeInt32(1), []byte{0xFF}))
+	f.Add([]byte{4, 4}, frame(encodeInt32(-1)))
+	f.Add([]byte{5}, frame(encodeInt32(1<<30)))
+	f.Add([]byte{0}, []byte{0xFF, 0xFF, 0xFF, 0xFF})
+	f.Fuzz(func(t *testing.T, ops []byte, data []byte) {
+		r := newReader(bytes.NewReader(data))
+		r.maxString = 16
+		r.maxBlob = 16
+		off := r.off
+		for _, op := range ops {
+			var err error
+			switch op % 6 {
+			case 0:
+				_, err = r.readByte()
+			case 1:
+				_, err = r.readInt32()
+			case 2:
+				_, err = r.readInt64()
+			case 3:
+				_, err = r.readDouble()
+			case 4:
+				var s string
+				s, err = r.readString()
+				if len(s) > r.maxString {
+					t.Fatalf("want string of at most %d bytes but have %d", r.maxString, len(s))
+				}
+			case 5:
+				var b []byte
+				b, err = r.readBlob()
+				if len(b) > r.maxBlob {
+					t.Fatalf("want blob of at most %d bytes but have %d", r.maxBlob, len(b))
+				}
+			}
+			if r.off < off || r.off > int64(len(data)) {
+				t.Fatalf("invalid offset %d after %d, data has %d bytes", r.off, off, len(data))
+			}
+			off = r.off
+			if err == nil {
+				continue
+			}
+			var protoErr *ProtocolError
+			if errors.As(err, &protoErr) {
+				if protoErr.Offset < 0 || protoErr.Offset > int64(len(data)) {
+					t.Fatalf("invalid error offset %d, data has %d bytes", protoErr.Offset, len(data))
+				}
+			} else if err != io.EOF && err != io.ErrUnexpectedEOF {
+				t.Fatalf("want ProtocolError or EOF but have %v", err)
+			}
+			return
+		}
+	})
+}
+
+func FuzzQueryResponse(f *testing.F) {
+	allTypes := []byte{ValInt32, ValInt64, ValDouble, ValString, ValBlob}
+	row := bytes.Join([][]byte{
+		{1},
+		{ValInt32}, encodeInt32(1),
+		{ValInt64}, encodeInt64(2),
+		{ValDouble}, encodeDouble(3.5),
+		{ValString}, encodeInt32(2), []byte("A\x00"),
+		{ValBlob}, encodeInt32(1), {0xFF},
+	}, nil)
+	nulls := []byte{1, ValNull, ValNull, ValNull, ValNull, ValNull}
+	f.Add(allTypes, frame(row, nulls, []byte{0}))
+	f.Add(allTypes, frame(row)[:20])
+	f.Add([]byte{ValString}, frame([]byte{1, ValString}, encodeInt32(-5)))
+	f.Add([]byte{ValBlob}, frame([]byte{1, ValBlob}, encodeInt32(1<<30)))
+	f.Fuzz(func(t *testing.T, coltypes []byte, data []byte) {
+		if len(coltypes) == 0 || len(coltypes) > 16 {
+			return
+		}
+		for i := range coltypes {
+			coltypes[i] = allTypes[int(coltypes[i])%len(allTypes)]
+		}
+		sq := &Sqinn{w: newWriter(io.Discard), r: newReader(bytes.NewReader(data)), noPanic: true}
+		sq.r.maxString = 16
+		sq.r.maxBlob = 16
+		err := sq.Query("SELECT 1", nil, coltypes, func(row int, values []Value) {
+			for i, val := range values {
+				if val.Type > ValBlob {
+					t.Fatalf("column %d: invalid type %d", i, val.Type)
+				}
+				if len(val.String) > 16 || len(val.Blob) > 16 {
+					t.Fatalf("column %d: want at most 16 bytes but have %d/%d", i, len(val.String), len(val.Blob))
+				}
+			}
+		})
+		if err == nil {
+			return
+		}
+		// sqinn errors and overflows are not protocol errors
+		if !errors.Is(err, ErrProtocol) && err != io.EOF && err != io.ErrUnexpectedEOF && !strings.HasPrefix(err.Error(), "sqinn: ") && !strings.HasPrefix(err.Error(), "row ") {
+			t.Fatalf("want ErrProtocol, EOF or sqinn error but have %v", err)
+		}
+		if errors.Is(err, ErrProtocol) && sq.broken == nil {
+			t.Fatalf("want broken instance after %v", err)
+		}
+	})
 }
 
 type errWriter struct {
@@ -808,6 +1039,19 @@ func (w *errWriter) Write(p []byte) (int, error) {
 	return w.n, w.err
 }
 
+func TestMaxFrameSize(t *testing.T) {
+	query := func(maxFrameSize int) error {
+		sq := MustLaunch(Options{MaxFrameSize: maxFrameSize})
+		defer sq.Close()
+		_, err := sq.QueryRows("SELECT randomblob(3000000)", nil, []byte{ValBlob})
+		return err
+	}
+	err := query(2_000_000)
+	isTrue(t, errors.Is(err, ErrProtocol), "want ErrProtocol but have %v", err)
+	isNoErr(t, query(4_000_000))
+	isNoErr(t, query(0))
+}
+
 func TestWriterErrors(t *testing.T) {
 	// write error
 	w := newWriter(&errWriter{0, fmt.Errorf("fake error")})
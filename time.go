@@ -0,0 +1,127 @@
+package sqinn
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// TimeFormat defines how time.Time values are stored in SQLite.
+type TimeFormat int
+
+const (
+	// TimeText stores a time as ValString in RFC 3339 format with
+	// nanoseconds and a fixed width, e.g. "2006-01-02T15:04:05.000000000+07:00".
+	// The zone offset is preserved. SQLite's date and time functions
+	// understand this format.
+	TimeText TimeFormat = iota
+
+	// TimeUnix stores a time as ValInt64 seconds since 1970-01-01 UTC.
+	// Sub-second precision and the zone offset are lost.
+	TimeUnix
+
+	// TimeUnixMilli stores a time as ValInt64 milliseconds since 1970-01-01 UTC.
+	// Sub-millisecond precision and the zone offset are lost.
+	TimeUnixMilli
+
+	// TimeJulian stores a time as ValDouble Julian day number, like
+	// SQLite's julianday() function does.
+	// Sub-millisecond precision and the zone offset are lost.
+	TimeJulian
+)
+
+// timeTextLayout is the layout for TimeText.
+const timeTextLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// unixEpochJulianDay is the Julian day number of 1970-01-01 00:00:00 UTC.
+const unixEpochJulianDay = 2440587.5
+
+// timeTextLayouts are the layouts that are accepted when parsing TimeText values.
+// Layouts without zone offset are UTC, like in SQLite.
+var timeTextLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TimeValue creates a Value that holds t in the given format.
+func TimeValue(t time.Time, format TimeFormat) Value {
+	switch format {
+	case TimeUnix:
+		return Int64Value(t.Unix())
+	case TimeUnixMilli:
+		return Int64Value(t.UnixMilli())
+	case TimeJulian:
+		return DoubleValue(unixEpochJulianDay + float64(t.UnixMilli())/(24*60*60*1000))
+	}
+	return StringValue(t.Format(timeTextLayout))
+}
+
+// ValueTime converts a Value that holds a time in the given format to a time.Time.
+//
+// For TimeText, it accepts RFC 3339 times and the formats of SQLite's
+// date and time functions, e.g. "2006-01-02 15:04:05". Times without zone
+// offset are UTC. The returned time has the parsed zone offset.
+//
+// For TimeUnix, TimeUnixMilli and TimeJulian, it accepts ValInt32, ValInt64
+// and ValDouble values. The returned time is UTC.
+//
+// It returns an error if v is NULL or cannot be converted.
+func ValueTime(v Value, format TimeFormat) (time.Time, error) {
+	if format == TimeText {
+		if v.Type != ValString {
+			return time.Time{}, fmt.Errorf("want ValString time but have value type %d", v.Type)
+		}
+		for _, layout := range timeTextLayouts {
+			if t, err := time.Parse(layout, v.String); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse time %q", v.String)
+	}
+	var f float64
+	switch v.Type {
+	case ValInt32:
+		f = float64(v.Int32)
+	case ValInt64:
+		f = float64(v.Int64)
+	case ValDouble:
+		f = v.Double
+	default:
+		return time.Time{}, fmt.Errorf("want numeric time but have value type %d", v.Type)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, fmt.Errorf("invalid time %v", f)
+	}
+	switch format {
+	case TimeUnix:
+		if v.Type == ValInt64 {
+			return time.Unix(v.Int64, 0).UTC(), nil
+		}
+		return time.UnixMilli(int64(math.Round(f * 1000))).UTC(), nil
+	case TimeUnixMilli:
+		if v.Type == ValInt64 {
+			return time.UnixMilli(v.Int64).UTC(), nil
+		}
+		return time.UnixMilli(int64(math.Round(f))).UTC(), nil
+	case TimeJulian:
+		return time.UnixMilli(int64(math.Round((f - unixEpochJulianDay) * (24 * 60 * 60 * 1000)))).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time format %d", format)
+}
+
+// NextTime returns the next value as time.Time, see ValueTime for details.
+// It returns false if the value was NULL, and an error if the value
+// cannot be converted.
+func (s *Scanner) NextTime(format TimeFormat) (time.Time, bool, error) {
+	v := s.Next()
+	if v.Type == ValNull {
+		return time.Time{}, false, nil
+	}
+	t, err := ValueTime(v, format)
+	return t, err == nil, err
+}
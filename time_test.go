@@ -0,0 +1,147 @@
+package sqinn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeValue(t *testing.T) {
+	tm := time.Date(2025, 8, 15, 13, 14, 15, 123456789, time.FixedZone("", 2*3600))
+	isEq(t, "2025-08-15T13:14:15.123456789+02:00", TimeValue(tm, TimeText).String)
+	isEq(t, "2025-08-15T11:14:15.000000000Z", TimeValue(tm.UTC().Truncate(time.Second), TimeText).String)
+	isEq(t, tm.Unix(), TimeValue(tm, TimeUnix).Int64)
+	isEq(t, tm.UnixMilli(), TimeValue(tm, TimeUnixMilli).Int64)
+	isEq(t, ValDouble, TimeValue(tm, TimeJulian).Type)
+	// roundtrip
+	for _, tc := range []struct {
+		format TimeFormat
+		want   time.Time
+	}{
+		{TimeText, tm},
+		{TimeUnix, tm.Truncate(time.Second).UTC()},
+		{TimeUnixMilli, tm.Truncate(time.Millisecond).UTC()},
+		{TimeJulian, tm.Truncate(time.Millisecond).UTC()},
+	} {
+		have, err := ValueTime(TimeValue(tm, tc.format), tc.format)
+		isNoErr(t, err)
+		isTrue(t, tc.want.Equal(have), "format %d: want %s but have %s", tc.format, tc.want, have)
+		_, wantOffset := tc.want.Zone()
+		_, haveOffset := have.Zone()
+		isEq(t, wantOffset, haveOffset)
+	}
+	// julian days keep millisecond precision
+	for ms := int64(-2_208_988_800_000); ms < 4_102_444_800_000; ms += 6_311_433_600_001 / 1000 {
+		want := time.UnixMilli(ms).UTC()
+		have, err := ValueTime(TimeValue(want, TimeJulian), TimeJulian)
+		isNoErr(t, err)
+		isEq(t, want, have)
+	}
+	// text formats
+	for _, s := range []string{
+		"2025-08-15T11:14:15.5Z",
+		"2025-08-15T13:14:15.5+02:00",
+		"2025-08-15 13:14:15.5+02:00",
+		"2025-08-15T11:14:15.5",
+		"2025-08-15 11:14:15.500",
+	} {
+		have, err := ValueTime(StringValue(s), TimeText)
+		isNoErr(t, err)
+		isTrue(t, have.Equal(time.Date(2025, 8, 15, 11, 14, 15, 500_000_000, time.UTC)), "%q: wrong time %s", s, have)
+	}
+	have, err := ValueTime(StringValue("2025-08-15"), TimeText)
+	isNoErr(t, err)
+	isEq(t, time.Date(2025, 8, 15, 0, 0, 0, 0, time.UTC), have)
+	// numeric formats
+	have, err = ValueTime(DoubleValue(1.5), TimeUnix)
+	isNoErr(t, err)
+	isEq(t, time.UnixMilli(1500).UTC(), have)
+	have, err = ValueTime(Int32Value(1500), TimeUnixMilli)
+	isNoErr(t, err)
+	isEq(t, time.UnixMilli(1500).UTC(), have)
+	have, err = ValueTime(DoubleValue(2440588.0), TimeJulian)
+	isNoErr(t, err)
+	isEq(t, time.Unix(12*60*60, 0).UTC(), have)
+	// errors
+	_, err = ValueTime(NullValue(), TimeText)
+	isErr(t, err, "want ValString time but have value type 0")
+	_, err = ValueTime(StringValue("yesterday"), TimeText)
+	isErr(t, err, `cannot parse time "yesterday"`)
+	_, err = ValueTime(StringValue("1"), TimeUnix)
+	isErr(t, err, "want numeric time but have value type 4")
+	_, err = ValueTime(Int32Value(1), TimeFormat(99))
+	isErr(t, err, "invalid time format 99")
+	// scanner
+	sc := Scan([]Value{NullValue(), TimeValue(tm, TimeUnix), StringValue("x")})
+	_, ok, err := sc.NextTime(TimeUnix)
+	isNoErr(t, err)
+	isEq(t, false, ok)
+	have, ok, err = sc.NextTime(TimeUnix)
+	isNoErr(t, err)
+	isEq(t, true, ok)
+	isEq(t, tm.Unix(), have.Unix())
+	_, ok, err = sc.NextTime(TimeText)
+	isErr(t, err, `cannot parse time "x"`)
+	isEq(t, false, ok)
+}
+
+func TestTimeSqlite(t *testing.T) {
+	sq := MustLaunch(Options{TimeFormat: TimeUnixMilli})
+	t.Cleanup(func() {
+		isNoErr(t, sq.Close())
+	})
+	tm := time.Date(2025, 8, 15, 13, 14, 15, 123456789, time.FixedZone("", -5*3600))
+	sqliteTime := tm.UTC().Format("2006-01-02 15:04:05.000")
+	// sqlite understands our times
+	sq.MustExecSql("CREATE TABLE times (txt TEXT, unix INTEGER, milli INTEGER, julian REAL, def INTEGER)")
+	def, err := sq.BindE([]any{tm})
+	isNoErr(t, err)
+	sq.MustExecParams("INSERT INTO times (txt, unix, milli, julian, def) VALUES (?,?,?,?,?)", 1, 5, []Value{
+		TimeValue(tm, TimeText),
+		TimeValue(tm, TimeUnix),
+		TimeValue(tm, TimeUnixMilli),
+		TimeValue(tm, TimeJulian),
+		def[0],
+	})
+	rows := sq.MustQueryRows(`SELECT
+		strftime('%Y-%m-%d %H:%M:%f', txt),
+		datetime(unix, 'unixepoch'),
+		strftime('%Y-%m-%d %H:%M:%f', milli / 1000.0, 'unixepoch'),
+		strftime('%Y-%m-%d %H:%M:%f', julian),
+		def
+		FROM times`, nil, []byte{ValString, ValString, ValString, ValString, ValInt64})
+	isEq(t, sqliteTime, rows[0][0].String)
+	isEq(t, tm.UTC().Format("2006-01-02 15:04:05"), rows[0][1].String)
+	isEq(t, sqliteTime, rows[0][2].String)
+	isEq(t, sqliteTime, rows[0][3].String)
+	isEq(t, tm.UnixMilli(), rows[0][4].Int64)
+	// scanning uses the time format of the instance, too
+	var have time.Time
+	isNoErr(t, sq.ScanInto(rows[0][4:], &have))
+	isEq(t, tm.UnixMilli(), have.UnixMilli())
+	var pt *time.Time
+	isNoErr(t, sq.Scan(rows[0][4:]).Scan(&pt))
+	isEq(t, tm.UnixMilli(), pt.UnixMilli())
+	isErr(t, ScanInto(rows[0][4:], &have), "column 0: want ValString time but have value type 2")
+	isEq(t, def[0].Int64, sq.Bind([]any{tm})[0].Int64)
+	isEq(t, ValString, Bind([]any{tm})[0].Type)
+	// we understand sqlite's times
+	const lit = "'2025-08-15 18:14:15.123'"
+	rows = sq.MustQueryRows("SELECT datetime("+lit+"), strftime('%Y-%m-%d %H:%M:%f', "+lit+"), unixepoch("+lit+"), unixepoch("+lit+", 'subsec'), julianday("+lit+")",
+		nil, []byte{ValString, ValString, ValInt64, ValDouble, ValDouble})
+	sc := Scan(rows[0])
+	for _, tc := range []struct {
+		format TimeFormat
+		want   time.Time
+	}{
+		{TimeText, tm.Truncate(time.Second)},
+		{TimeText, tm.Truncate(time.Millisecond)},
+		{TimeUnix, tm.Truncate(time.Second)},
+		{TimeUnix, tm.Truncate(time.Millisecond)},
+		{TimeJulian, tm.Truncate(time.Millisecond)},
+	} {
+		have, ok, err := sc.NextTime(tc.format)
+		isNoErr(t, err)
+		isEq(t, true, ok)
+		isTrue(t, tc.want.Equal(have), "format %d: want %s but have %s", tc.format, tc.want, have)
+	}
+}